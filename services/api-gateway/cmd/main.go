@@ -16,6 +16,9 @@ import (
 	authclient "github.com/vasapolrittideah/optimize-api/services/auth-service/pkg/client"
 	"github.com/vasapolrittideah/optimize-api/shared/discovery"
 	"github.com/vasapolrittideah/optimize-api/shared/logger"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
+	"github.com/vasapolrittideah/optimize-api/shared/utilities"
+	"github.com/vasapolrittideah/optimize-api/shared/validator"
 )
 
 func main() {
@@ -35,10 +38,17 @@ func main() {
 		logger.Fatal().Err(err).Msg("failed to create auth service client")
 	}
 
+	trustedProxies, err := utilities.ParseTrustedProxies(apiGatewayCfg.TrustedProxies)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to parse trusted proxies")
+	}
+
+	validator.RegisterPasswordPolicy(security.NewPasswordPolicy(logger))
+
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(utilities.ForwardClientMetadata(trustedProxies))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
@@ -50,7 +60,7 @@ func main() {
 		Handler:      r,
 	}
 
-	authHandler := httphandler.NewAuthHTTPHandler(r, logger, authServiceClient)
+	authHandler := httphandler.NewAuthHTTPHandler(r, logger, authServiceClient, &apiGatewayCfg.OAuthCfg)
 	authHandler.RegisterRoutes()
 
 	serverErrors := make(chan error, 1)
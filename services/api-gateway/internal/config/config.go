@@ -1,20 +1,32 @@
 package config
 
 import (
+	"time"
+
 	"github.com/caarlos0/env/v11"
 	"github.com/rs/zerolog"
 )
 
 type APIGatewayConfig struct {
-	Environment    string `env:"ENVIRONMENT"`
-	Address        string `env:"API_GATEWAY_ADDRESS"`
+	Environment    string   `env:"ENVIRONMENT"`
+	Address        string   `env:"API_GATEWAY_ADDRESS"`
+	TrustedProxies []string `env:"TRUSTED_PROXIES"`
 	AuthServiceCfg AuthServiceConfig
+	OAuthCfg       OAuthConfig
 }
 
 type AuthServiceConfig struct {
 	Name string `env:"AUTH_SERVICE_NAME"`
 }
 
+// OAuthConfig contains the configuration of the cookie that binds an OAuth sign-in to the
+// browser that started it. The state expiry is read from the same variable as in the auth
+// service, and the cookie should only be made insecure for local development over HTTP.
+type OAuthConfig struct {
+	StateExpiresIn time.Duration `env:"OAUTH_STATE_EXPIRES_IN"    envDefault:"10m"`
+	SecureCookie   bool          `env:"OAUTH_STATE_SECURE_COOKIE" envDefault:"true"`
+}
+
 func NewAPIGatewayConfig(logger *zerolog.Logger) *APIGatewayConfig {
 	cfg, err := env.ParseAs[APIGatewayConfig]()
 	if err != nil {
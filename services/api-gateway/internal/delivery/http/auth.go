@@ -1,45 +1,131 @@
 package http
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog"
 
+	"github.com/vasapolrittideah/optimize-api/services/api-gateway/internal/config"
 	"github.com/vasapolrittideah/optimize-api/services/api-gateway/internal/payload"
 	authclient "github.com/vasapolrittideah/optimize-api/services/auth-service/pkg/client"
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
 	authpbv1 "github.com/vasapolrittideah/optimize-api/shared/protos/auth/v1"
 	"github.com/vasapolrittideah/optimize-api/shared/utilities"
 	"github.com/vasapolrittideah/optimize-api/shared/validator"
 )
 
+// oauthStateCookie is the cookie that binds an OAuth sign-in to the browser that started it,
+// so that a callback cannot be replayed in another browser to sign it in.
+const oauthStateCookie = "oauth_state"
+
+// jwksCacheMaxAge is how long clients may cache the JWKS. Keys are staged well before they
+// are used to sign, so a cached JWKS still contains the key of any newly issued token.
+const jwksCacheMaxAge = 5 * time.Minute
+
 type AuthHTTPHandler struct {
 	router            *chi.Mux
 	logger            *zerolog.Logger
 	authServiceClient *authclient.AuthServiceClient
+	oauthCfg          *config.OAuthConfig
 }
 
 func NewAuthHTTPHandler(
 	router *chi.Mux,
 	logger *zerolog.Logger,
 	authServiceClient *authclient.AuthServiceClient,
+	oauthCfg *config.OAuthConfig,
 ) *AuthHTTPHandler {
 	handler := &AuthHTTPHandler{
 		router:            router,
 		logger:            logger,
 		authServiceClient: authServiceClient,
+		oauthCfg:          oauthCfg,
 	}
 
 	return handler
 }
 
 func (h *AuthHTTPHandler) RegisterRoutes() {
+	h.router.Get("/.well-known/jwks.json", h.getJWKS)
 	h.router.Route("/auth", func(r chi.Router) {
 		r.Post("/signin", h.signIn)
 		r.Post("/signup", h.signUp)
+		r.Post("/refresh", h.refreshTokens)
+		r.Post("/signout", h.signOut)
+		r.Post("/signout-all", h.signOutAll)
+		r.Get("/sessions", h.listSessions)
+		r.Post("/verify-email", h.verifyEmail)
+		r.Post("/verify-email/resend", h.resendVerification)
+		r.Post("/password/forgot", h.forgotPassword)
+		r.Post("/password/reset", h.resetPassword)
+		r.Post("/password/change", h.changePassword)
+		r.Post("/magic-link", h.requestMagicLink)
+		r.Post("/magic-link/consume", h.consumeMagicLink)
+		r.Route("/mfa", func(r chi.Router) {
+			r.Post("/verify", h.verifyMFA)
+			r.Post("/totp/enroll", h.enrollTOTP)
+			r.Post("/totp/confirm", h.confirmTOTP)
+			r.Post("/totp/disable", h.disableTOTP)
+			r.Get("/recovery-codes", h.countRecoveryCodes)
+			r.Post("/recovery-codes", h.generateRecoveryCodes)
+			r.Post("/recovery-codes/redeem", h.redeemRecoveryCode)
+		})
+		r.Route("/oauth/{provider}", func(r chi.Router) {
+			r.Get("/start", h.startOAuth)
+			r.Get("/callback", h.completeOAuth)
+		})
+		r.Route("/passkeys", func(r chi.Router) {
+			r.Post("/register/begin", h.beginPasskeyRegistration)
+			r.Post("/register/finish", h.finishPasskeyRegistration)
+			r.Post("/login/begin", h.beginPasskeyLogin)
+			r.Post("/login/finish", h.finishPasskeyLogin)
+		})
+		r.Route("/identities", func(r chi.Router) {
+			r.Get("/", h.listIdentities)
+			r.Post("/{provider}/link", h.linkIdentity)
+			r.Delete("/{identityID}", h.unlinkIdentity)
+		})
 	})
 }
 
+// getJWKS serves the public keys that access tokens are verified with. It is written as a plain
+// JWKS document rather than an API response, since JWKS clients expect the standard format.
+func (h *AuthHTTPHandler) getJWKS(w http.ResponseWriter, r *http.Request) {
+	grpcResp, err := h.authServiceClient.Client.GetJWKS(r.Context(), &authpbv1.GetJWKSRequest{})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	jwks := &auth.JWKS{
+		Keys: make([]auth.JWK, 0, len(grpcResp.Keys)),
+	}
+	for _, key := range grpcResp.Keys {
+		jwks.Keys = append(jwks.Keys, auth.JWK{
+			KeyType:   key.Kty,
+			KeyID:     key.Kid,
+			Use:       key.Use,
+			Algorithm: key.Alg,
+			N:         key.N,
+			E:         key.E,
+			Curve:     key.Crv,
+			X:         key.X,
+			Y:         key.Y,
+		})
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksCacheMaxAge.Seconds())))
+	if err := utilities.WriteJSON(w, http.StatusOK, jwks); err != nil {
+		h.logger.Error().Err(err).
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Msg("failed to write JWKS response")
+	}
+}
+
 func (h *AuthHTTPHandler) signIn(w http.ResponseWriter, r *http.Request) {
 	var req payload.SignInRequest
 	if err := utilities.ReadJSON(w, r, &req); err != nil {
@@ -64,6 +150,8 @@ func (h *AuthHTTPHandler) signIn(w http.ResponseWriter, r *http.Request) {
 	payload := &payload.SignInResponse{
 		AccessToken:  grpcResp.AccessToken,
 		RefreshToken: grpcResp.RefreshToken,
+		MFARequired:  grpcResp.MfaRequired,
+		MFAToken:     grpcResp.MfaToken,
 	}
 
 	utilities.WriteSuccessResponse(w, r, payload, h.logger)
@@ -92,9 +180,753 @@ func (h *AuthHTTPHandler) signUp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	payload := &payload.SignUpResponse{
+		AccessToken:          grpcResp.AccessToken,
+		RefreshToken:         grpcResp.RefreshToken,
+		VerificationRequired: grpcResp.VerificationRequired,
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) refreshTokens(w http.ResponseWriter, r *http.Request) {
+	var req payload.RefreshTokensRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.RefreshTokens(r.Context(), &authpbv1.RefreshTokensRequest{
+		RefreshToken: req.RefreshToken,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	payload := &payload.RefreshTokensResponse{
+		AccessToken:  grpcResp.AccessToken,
+		RefreshToken: grpcResp.RefreshToken,
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) signOut(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	_, err := h.authServiceClient.Client.SignOut(r.Context(), &authpbv1.SignOutRequest{
+		AccessToken: accessToken,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	utilities.WriteSuccessResponse(w, r, nil, h.logger)
+}
+
+func (h *AuthHTTPHandler) signOutAll(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	_, err := h.authServiceClient.Client.SignOutAll(r.Context(), &authpbv1.SignOutAllRequest{
+		AccessToken: accessToken,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	utilities.WriteSuccessResponse(w, r, nil, h.logger)
+}
+
+func (h *AuthHTTPHandler) listSessions(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.ListSessions(r.Context(), &authpbv1.ListSessionsRequest{
+		AccessToken: accessToken,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	sessions := make([]payload.SessionResponse, 0, len(grpcResp.Sessions))
+	for _, session := range grpcResp.Sessions {
+		sessions = append(sessions, payload.SessionResponse{
+			ID:         session.Id,
+			IPAddress:  session.IpAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt.AsTime(),
+			LastUsedAt: session.LastUsedAt.AsTime(),
+			Current:    session.Current,
+		})
+	}
+
+	payload := &payload.ListSessionsResponse{
+		Sessions: sessions,
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req payload.VerifyEmailRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	_, err := h.authServiceClient.Client.VerifyEmail(r.Context(), &authpbv1.VerifyEmailRequest{
+		Email: req.Email,
+		Code:  req.Code,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	utilities.WriteSuccessResponse(w, r, nil, h.logger)
+}
+
+func (h *AuthHTTPHandler) resendVerification(w http.ResponseWriter, r *http.Request) {
+	var req payload.ResendVerificationRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	_, err := h.authServiceClient.Client.ResendVerification(r.Context(), &authpbv1.ResendVerificationRequest{
+		Email: req.Email,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	utilities.WriteSuccessResponse(w, r, nil, h.logger)
+}
+
+func (h *AuthHTTPHandler) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req payload.ForgotPasswordRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	_, err := h.authServiceClient.Client.RequestPasswordReset(r.Context(), &authpbv1.RequestPasswordResetRequest{
+		Email: req.Email,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	utilities.WriteSuccessResponse(w, r, nil, h.logger)
+}
+
+func (h *AuthHTTPHandler) requestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req payload.RequestMagicLinkRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	_, err := h.authServiceClient.Client.RequestMagicLink(r.Context(), &authpbv1.RequestMagicLinkRequest{
+		Email:      req.Email,
+		CreateUser: req.CreateUser,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	utilities.WriteSuccessResponse(w, r, nil, h.logger)
+}
+
+func (h *AuthHTTPHandler) consumeMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req payload.ConsumeMagicLinkRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.ConsumeMagicLink(r.Context(), &authpbv1.ConsumeMagicLinkRequest{
+		Token: req.Token,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	payload := &payload.SignInResponse{
+		AccessToken:  grpcResp.AccessToken,
+		RefreshToken: grpcResp.RefreshToken,
+		MFARequired:  grpcResp.MfaRequired,
+		MFAToken:     grpcResp.MfaToken,
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var req payload.ResetPasswordRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	_, err := h.authServiceClient.Client.ResetPassword(r.Context(), &authpbv1.ResetPasswordRequest{
+		Token:       req.Token,
+		NewPassword: req.NewPassword,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	utilities.WriteSuccessResponse(w, r, nil, h.logger)
+}
+
+func (h *AuthHTTPHandler) changePassword(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	var req payload.ChangePasswordRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	_, err := h.authServiceClient.Client.ChangePassword(r.Context(), &authpbv1.ChangePasswordRequest{
+		AccessToken:         accessToken,
+		CurrentPassword:     req.CurrentPassword,
+		NewPassword:         req.NewPassword,
+		RevokeOtherSessions: req.RevokeOtherSessions,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	utilities.WriteSuccessResponse(w, r, nil, h.logger)
+}
+
+func (h *AuthHTTPHandler) verifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req payload.VerifyMFARequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.VerifyMFA(r.Context(), &authpbv1.VerifyMFARequest{
+		MfaToken: req.MFAToken,
+		Code:     req.Code,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	payload := &payload.VerifyMFAResponse{
+		AccessToken:  grpcResp.AccessToken,
+		RefreshToken: grpcResp.RefreshToken,
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) enrollTOTP(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.EnrollTOTP(r.Context(), &authpbv1.EnrollTOTPRequest{
+		AccessToken: accessToken,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	payload := &payload.EnrollTOTPResponse{
+		Secret: grpcResp.Secret,
+		URI:    grpcResp.Uri,
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) confirmTOTP(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	var req payload.ConfirmTOTPRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	_, err := h.authServiceClient.Client.ConfirmTOTP(r.Context(), &authpbv1.ConfirmTOTPRequest{
+		AccessToken: accessToken,
+		Code:        req.Code,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	utilities.WriteSuccessResponse(w, r, nil, h.logger)
+}
+
+func (h *AuthHTTPHandler) disableTOTP(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	var req payload.DisableTOTPRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	_, err := h.authServiceClient.Client.DisableTOTP(r.Context(), &authpbv1.DisableTOTPRequest{
+		AccessToken: accessToken,
+		Code:        req.Code,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	utilities.WriteSuccessResponse(w, r, nil, h.logger)
+}
+
+func (h *AuthHTTPHandler) generateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.GenerateRecoveryCodes(
+		r.Context(),
+		&authpbv1.GenerateRecoveryCodesRequest{
+			AccessToken: accessToken,
+		},
+	)
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	payload := &payload.GenerateRecoveryCodesResponse{
+		Codes: grpcResp.Codes,
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) redeemRecoveryCode(w http.ResponseWriter, r *http.Request) {
+	var req payload.RedeemRecoveryCodeRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.RedeemRecoveryCode(r.Context(), &authpbv1.RedeemRecoveryCodeRequest{
+		Email: req.Email,
+		Code:  req.Code,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	payload := &payload.RedeemRecoveryCodeResponse{
+		AccessToken:            grpcResp.AccessToken,
+		RefreshToken:           grpcResp.RefreshToken,
+		PasswordChangeRequired: grpcResp.PasswordChangeRequired,
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) countRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.CountRecoveryCodes(r.Context(), &authpbv1.CountRecoveryCodesRequest{
+		AccessToken: accessToken,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	payload := &payload.CountRecoveryCodesResponse{
+		RemainingCodes: int(grpcResp.RemainingCodes),
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) startOAuth(w http.ResponseWriter, r *http.Request) {
+	grpcResp, err := h.authServiceClient.Client.BeginOAuth(r.Context(), &authpbv1.BeginOAuthRequest{
+		Provider: chi.URLParam(r, "provider"),
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	h.setOAuthStateCookie(w, grpcResp.State)
+
+	http.Redirect(w, r, grpcResp.AuthorizationUrl, http.StatusFound)
+}
+
+func (h *AuthHTTPHandler) completeOAuth(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if providerErr := query.Get("error"); providerErr != "" {
+		utilities.WriteRequestErrorResponse(w, r, "OAuth provider returned an error: "+providerErr, h.logger)
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil || state == "" || cookie.Value != state {
+		utilities.WriteRequestErrorResponse(w, r, "invalid OAuth state", h.logger)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Path:     "/auth/oauth",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.oauthCfg.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	grpcResp, err := h.authServiceClient.Client.CompleteOAuth(r.Context(), &authpbv1.CompleteOAuthRequest{
+		Provider: chi.URLParam(r, "provider"),
+		State:    state,
+		Code:     query.Get("code"),
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	payload := &payload.CompleteOAuthResponse{
+		AccessToken:    grpcResp.AccessToken,
+		RefreshToken:   grpcResp.RefreshToken,
+		MFARequired:    grpcResp.MfaRequired,
+		MFAToken:       grpcResp.MfaToken,
+		IdentityLinked: grpcResp.IdentityLinked,
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) listIdentities(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.ListIdentities(r.Context(), &authpbv1.ListIdentitiesRequest{
+		AccessToken: accessToken,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	identities := make([]payload.IdentityResponse, 0, len(grpcResp.Identities))
+	for _, identity := range grpcResp.Identities {
+		identityResp := payload.IdentityResponse{
+			ID:        identity.Id,
+			Provider:  identity.Provider,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt.AsTime(),
+		}
+		if identity.LastLoginAt != nil {
+			lastLoginAt := identity.LastLoginAt.AsTime()
+			identityResp.LastLoginAt = &lastLoginAt
+		}
+
+		identities = append(identities, identityResp)
+	}
+
+	payload := &payload.ListIdentitiesResponse{
+		Identities: identities,
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+// linkIdentity starts linking a provider identity to the current user. The client sends the
+// user to the returned authorization URL and the provider redirects back to the OAuth callback.
+func (h *AuthHTTPHandler) linkIdentity(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.LinkIdentity(r.Context(), &authpbv1.LinkIdentityRequest{
+		AccessToken: accessToken,
+		Provider:    chi.URLParam(r, "provider"),
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	h.setOAuthStateCookie(w, grpcResp.State)
+
+	payload := &payload.LinkIdentityResponse{
+		AuthorizationURL: grpcResp.AuthorizationUrl,
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) unlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	_, err := h.authServiceClient.Client.UnlinkIdentity(r.Context(), &authpbv1.UnlinkIdentityRequest{
+		AccessToken: accessToken,
+		IdentityId:  chi.URLParam(r, "identityID"),
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	utilities.WriteSuccessResponse(w, r, nil, h.logger)
+}
+
+func (h *AuthHTTPHandler) beginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.BeginPasskeyRegistration(
+		r.Context(),
+		&authpbv1.BeginPasskeyRegistrationRequest{
+			AccessToken: accessToken,
+		},
+	)
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	payload := &payload.PasskeyChallengeResponse{
+		ChallengeID: grpcResp.ChallengeId,
+		Options:     grpcResp.Options,
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) finishPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := utilities.ReadBearerToken(r)
+	if !ok {
+		utilities.WriteUnauthorizedErrorResponse(w, r, "missing access token", h.logger)
+		return
+	}
+
+	var req payload.FinishPasskeyRegistrationRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.FinishPasskeyRegistration(
+		r.Context(),
+		&authpbv1.FinishPasskeyRegistrationRequest{
+			AccessToken: accessToken,
+			ChallengeId: req.ChallengeID,
+			Credential:  req.Credential,
+			Name:        req.Name,
+		},
+	)
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	payload := &payload.PasskeyResponse{
+		ID:        grpcResp.Id,
+		Name:      grpcResp.Name,
+		CreatedAt: grpcResp.CreatedAt.AsTime(),
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) beginPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	var req payload.BeginPasskeyLoginRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.BeginPasskeyLogin(r.Context(), &authpbv1.BeginPasskeyLoginRequest{
+		MfaToken: req.MFAToken,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	payload := &payload.PasskeyChallengeResponse{
+		ChallengeID: grpcResp.ChallengeId,
+		Options:     grpcResp.Options,
+	}
+
+	utilities.WriteSuccessResponse(w, r, payload, h.logger)
+}
+
+func (h *AuthHTTPHandler) finishPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	var req payload.FinishPasskeyLoginRequest
+	if err := utilities.ReadJSON(w, r, &req); err != nil {
+		utilities.WriteRequestErrorResponse(w, r, err.Error(), h.logger)
+		return
+	}
+
+	if errs := validator.ValidateStruct(req); errs != nil {
+		utilities.WriteValidationErrorResponse(w, r, errs, h.logger)
+		return
+	}
+
+	grpcResp, err := h.authServiceClient.Client.FinishPasskeyLogin(r.Context(), &authpbv1.FinishPasskeyLoginRequest{
+		ChallengeId: req.ChallengeID,
+		Credential:  req.Credential,
+	})
+	if err != nil {
+		utilities.WriteInternalErrorResponse(w, r, err, h.logger)
+		return
+	}
+
+	payload := &payload.FinishPasskeyLoginResponse{
 		AccessToken:  grpcResp.AccessToken,
 		RefreshToken: grpcResp.RefreshToken,
 	}
 
 	utilities.WriteSuccessResponse(w, r, payload, h.logger)
 }
+
+// setOAuthStateCookie binds the state of an OAuth flow to the browser that started it.
+// The cookie expires together with the state in the auth service.
+func (h *AuthHTTPHandler) setOAuthStateCookie(w http.ResponseWriter, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/auth/oauth",
+		MaxAge:   int(h.oauthCfg.StateExpiresIn.Seconds()),
+		HttpOnly: true,
+		Secure:   h.oauthCfg.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -1,22 +1,194 @@
 package payload
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type SignInRequest struct {
 	Email    string `json:"email"    validate:"required,email"`
 	Password string `json:"password" validate:"required"`
 }
 
+// SignInResponse carries either the session tokens or, when the account requires a
+// second factor, an MFA token to exchange through the MFA verification route.
 type SignInResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	MFARequired  bool   `json:"mfa_required"`
+	MFAToken     string `json:"mfa_token,omitempty"`
 }
 
 type SignUpRequest struct {
 	Email    string `json:"email"     validate:"required,email"`
-	Password string `json:"password"  validate:"required"`
+	Password string `json:"password"  validate:"required,password"`
 	FullName string `json:"full_name" validate:"required"`
 }
 
 type SignUpResponse struct {
+	AccessToken          string `json:"access_token,omitempty"`
+	RefreshToken         string `json:"refresh_token,omitempty"`
+	VerificationRequired bool   `json:"verification_required"`
+}
+
+type RefreshTokensRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type RefreshTokensResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"`
+}
+
+type ListSessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+type VerifyEmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Code  string `json:"code"  validate:"required,numeric,len=6"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// RequestMagicLinkRequest asks for a sign-in link to be emailed. CreateUser lets apps with
+// email-only login register unknown addresses once the link is used.
+type RequestMagicLinkRequest struct {
+	Email      string `json:"email" validate:"required,email"`
+	CreateUser bool   `json:"create_user"`
+}
+
+type ConsumeMagicLinkRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"        validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,password"`
+}
+
+// ChangePasswordRequest requires the current password unless the user signed in with a
+// recovery code and must choose a new password, in which case it is ignored.
+type ChangePasswordRequest struct {
+	CurrentPassword     string `json:"current_password"`
+	NewPassword         string `json:"new_password" validate:"required,password"`
+	RevokeOtherSessions bool   `json:"revoke_other_sessions"`
+}
+
+type EnrollTOTPResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type ConfirmTOTPRequest struct {
+	Code string `json:"code" validate:"required,numeric,len=6"`
+}
+
+type DisableTOTPRequest struct {
+	Code string `json:"code" validate:"required,numeric,len=6"`
+}
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code"      validate:"required,numeric,len=6"`
+}
+
+type VerifyMFAResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type GenerateRecoveryCodesResponse struct {
+	Codes []string `json:"codes"`
+}
+
+type RedeemRecoveryCodeRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Code  string `json:"code"  validate:"required"`
+}
+
+type RedeemRecoveryCodeResponse struct {
+	AccessToken            string `json:"access_token"`
+	RefreshToken           string `json:"refresh_token"`
+	PasswordChangeRequired bool   `json:"password_change_required"`
+}
+
+type CountRecoveryCodesResponse struct {
+	RemainingCodes int `json:"remaining_codes"`
+}
+
+// CompleteOAuthResponse carries the result of an OAuth callback. Signing in yields the same
+// fields as SignInResponse, while linking an identity to the current user sets IdentityLinked.
+type CompleteOAuthResponse struct {
+	AccessToken    string `json:"access_token,omitempty"`
+	RefreshToken   string `json:"refresh_token,omitempty"`
+	MFARequired    bool   `json:"mfa_required"`
+	MFAToken       string `json:"mfa_token,omitempty"`
+	IdentityLinked bool   `json:"identity_linked"`
+}
+
+type IdentityResponse struct {
+	ID          string     `json:"id"`
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type ListIdentitiesResponse struct {
+	Identities []IdentityResponse `json:"identities"`
+}
+
+type LinkIdentityResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// PasskeyChallengeResponse carries the options to pass to the WebAuthn API of the browser and
+// the ID to send back with the resulting credential to finish the ceremony.
+type PasskeyChallengeResponse struct {
+	ChallengeID string          `json:"challenge_id"`
+	Options     json.RawMessage `json:"options"`
+}
+
+type FinishPasskeyRegistrationRequest struct {
+	ChallengeID string          `json:"challenge_id" validate:"required"`
+	Credential  json.RawMessage `json:"credential"   validate:"required"`
+	Name        string          `json:"name"         validate:"max=64"`
+}
+
+type PasskeyResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BeginPasskeyLoginRequest starts a passkey sign-in. Without an MFA token the passkey is used
+// to sign in without a password, otherwise it is the second factor of the sign-in that returned the token.
+type BeginPasskeyLoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+type FinishPasskeyLoginRequest struct {
+	ChallengeID string          `json:"challenge_id" validate:"required"`
+	Credential  json.RawMessage `json:"credential"   validate:"required"`
+}
+
+type FinishPasskeyLoginResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 }
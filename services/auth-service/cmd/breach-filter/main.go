@@ -0,0 +1,122 @@
+// Command breach-filter builds the bloom filter file used by the breached password check
+// from a list of SHA-1 password hashes, one per line. Lines in the "<hash>:<count>" format
+// published by Have I Been Pwned are accepted as well.
+//
+// Usage:
+//
+//	go run ./services/auth-service/cmd/breach-filter -input pwned-passwords-sha1.txt -output breached.bf
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/vasapolrittideah/optimize-api/shared/logger"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
+)
+
+func main() {
+	input := flag.String("input", "", "path to the SHA-1 hash list")
+	output := flag.String("output", "breached-passwords.bf", "path to write the bloom filter to")
+	falsePositiveRate := flag.Float64("fp-rate", 0.001, "false positive rate of the bloom filter")
+	flag.Parse()
+
+	logger := logger.New()
+
+	if *input == "" {
+		logger.Fatal().Msg("missing -input flag")
+	}
+
+	entries, err := countHashes(*input)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to count hashes")
+	}
+
+	filter := security.NewBloomFilter(entries, *falsePositiveRate)
+
+	added, err := addHashes(*input, filter)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to read hashes")
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create output file")
+	}
+
+	size, err := filter.WriteTo(file)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to write bloom filter")
+	}
+
+	// Close flushes the filter to disk, so a failure leaves the output file incomplete.
+	if err := file.Close(); err != nil {
+		logger.Fatal().Err(err).Msg("failed to close output file")
+	}
+
+	logger.Info().
+		Uint64("hashes", added).
+		Int64("bytes", size).
+		Str("output", *output).
+		Msg("bloom filter written")
+}
+
+// countHashes counts the non-empty lines of the hash list so the filter can be sized up front.
+func countHashes(path string) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var count uint64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			count++
+		}
+	}
+
+	return count, scanner.Err()
+}
+
+// addHashes adds every hash of the list to the filter and returns how many were added.
+func addHashes(path string, filter *security.BloomFilter) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var added uint64
+	var line uint64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(text, ":")
+
+		var digest [sha1.Size]byte
+		if len(hash) != hex.EncodedLen(sha1.Size) {
+			return added, fmt.Errorf("line %d: invalid SHA-1 hash %q", line, hash)
+		}
+		if _, err := hex.Decode(digest[:], []byte(hash)); err != nil {
+			return added, fmt.Errorf("line %d: invalid SHA-1 hash %q: %w", line, hash, err)
+		}
+
+		filter.Add(digest)
+		added++
+	}
+
+	return added, scanner.Err()
+}
@@ -7,6 +7,7 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/go-webauthn/webauthn/webauthn"
 	"google.golang.org/grpc"
 
 	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/config"
@@ -17,6 +18,11 @@ import (
 	"github.com/vasapolrittideah/optimize-api/shared/database"
 	"github.com/vasapolrittideah/optimize-api/shared/discovery"
 	"github.com/vasapolrittideah/optimize-api/shared/logger"
+	"github.com/vasapolrittideah/optimize-api/shared/mailer"
+	"github.com/vasapolrittideah/optimize-api/shared/oauth"
+	"github.com/vasapolrittideah/optimize-api/shared/operator"
+	"github.com/vasapolrittideah/optimize-api/shared/ratelimit"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
 	"github.com/vasapolrittideah/optimize-api/shared/utilities"
 )
 
@@ -53,18 +59,123 @@ func main() {
 		}
 	}()
 
+	accessTokenKeySet, err := auth.LoadKeySet(authServiceCfg.Token.AccessTokenKeys)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to load access token keys")
+	}
+
+	mailSender := mailer.New(logger)
+
+	oauthProviders := oauth.New(ctx, logger)
+
+	encryptor, err := security.NewEncryptor(authServiceCfg.MFA.EncryptionKey)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create MFA secret encryptor")
+	}
+
+	// Passkeys are only enabled once a relying party is configured.
+	var webAuthn *webauthn.WebAuthn
+	if authServiceCfg.WebAuthn.RPID != "" {
+		rpDisplayName := authServiceCfg.WebAuthn.RPDisplayName
+		if rpDisplayName == "" {
+			rpDisplayName = authServiceCfg.Token.Issuer
+		}
+
+		challengeTimeout := webauthn.TimeoutConfig{
+			Enforce: true,
+			Timeout: authServiceCfg.WebAuthn.ChallengeExpiresIn,
+		}
+		webAuthn, err = webauthn.New(&webauthn.Config{
+			RPID:          authServiceCfg.WebAuthn.RPID,
+			RPDisplayName: rpDisplayName,
+			RPOrigins:     authServiceCfg.WebAuthn.RPOrigins,
+			Timeouts: webauthn.TimeoutsConfig{
+				Login:        challengeTimeout,
+				Registration: challengeTimeout,
+			},
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create WebAuthn relying party")
+		}
+	}
+
+	identityRepo := mongoRepo.NewIdentityMongoRepository(ctx, logger, mongodb.GetDatabase())
+	sessionRepo := mongoRepo.NewSessionMongoRepository(ctx, logger, mongodb.GetDatabase())
+	userRepo := mongoRepo.NewUserMongoRepository(ctx, logger, mongodb.GetDatabase())
+	oneTimeTokenRepo := mongoRepo.NewOneTimeTokenMongoRepository(ctx, logger, mongodb.GetDatabase())
+	loginAttemptRepo := mongoRepo.NewLoginAttemptMongoRepository(ctx, logger, mongodb.GetDatabase())
+	oauthStateRepo := mongoRepo.NewOAuthStateMongoRepository(ctx, logger, mongodb.GetDatabase())
+	webAuthnCredentialRepo := mongoRepo.NewWebAuthnCredentialMongoRepository(ctx, logger, mongodb.GetDatabase())
+	webAuthnChallengeRepo := mongoRepo.NewWebAuthnChallengeMongoRepository(ctx, logger, mongodb.GetDatabase())
+	signingKeyRepo := mongoRepo.NewSigningKeyMongoRepository(ctx, logger, mongodb.GetDatabase())
+
+	// Asymmetric signing keys are shared between instances through the database so that they
+	// can be rotated at runtime. A shared secret is only held in memory.
+	var signingKeyStore auth.KeyStore
+	if authServiceCfg.Token.AccessTokenKeys.Algorithm != auth.AlgorithmHS256 {
+		signingKeyEncryptor, err := security.NewEncryptor(authServiceCfg.Token.SigningKeyEncryptionKey)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create signing key encryptor")
+		}
+
+		signingKeyStore = usecase.NewSigningKeyStore(signingKeyRepo, signingKeyEncryptor)
+	}
+
+	accessTokenKeys := auth.NewKeyManager(signingKeyStore, authServiceCfg.Token.SigningKeyOverlap)
+	if err := accessTokenKeys.Bootstrap(ctx, accessTokenKeySet); err != nil {
+		logger.Fatal().Err(err).Msg("failed to load access token signing keys")
+	}
+	go accessTokenKeys.Run(ctx, logger, authServiceCfg.Token.SigningKeyRefreshInterval)
+
+	revokedSessionRepo := mongoRepo.NewRevokedSessionMongoRepository(ctx, logger, mongodb.GetDatabase())
+
+	revocations := auth.NewRevocationList(usecase.NewRevocationSource(revokedSessionRepo))
+	if err := revocations.Sync(ctx); err != nil {
+		logger.Fatal().Err(err).Msg("failed to load session revocations")
+	}
+	go revocations.Run(ctx, logger, authServiceCfg.Token.RevocationSyncInterval)
+
 	jwtAuthenticator := auth.NewJWTAuthenticator(
 		authServiceCfg.Token.Issuer,
 		authServiceCfg.Token.Issuer,
+		revocations,
 	)
 
-	identityRepo := mongoRepo.NewIdentityMongoRepository(mongodb.GetDatabase())
-	sessionRepo := mongoRepo.NewSessionMongoRepository(mongodb.GetDatabase())
-	userRepo := mongoRepo.NewUserMongoRepository(ctx, logger, mongodb.GetDatabase())
+	authUsecase := usecase.NewAuthUsecase(
+		logger,
+		identityRepo,
+		sessionRepo,
+		userRepo,
+		oneTimeTokenRepo,
+		loginAttemptRepo,
+		oauthStateRepo,
+		webAuthnCredentialRepo,
+		webAuthnChallengeRepo,
+		revokedSessionRepo,
+		jwtAuthenticator,
+		accessTokenKeys,
+		revocations,
+		oauthProviders,
+		webAuthn,
+		mailSender,
+		encryptor,
+		security.NewPasswordHasher(logger),
+		security.NewPasswordPolicy(logger),
+		security.NewBreachChecker(logger),
+		authServiceCfg,
+	)
 
-	authUsecase := usecase.NewAuthUsecase(identityRepo, sessionRepo, userRepo, jwtAuthenticator, authServiceCfg)
+	rateLimits, err := ratelimit.ParseLimits(authServiceCfg.RateLimit.Limits)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to parse rate limits")
+	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			operator.UnaryServerInterceptor(logger, authServiceCfg.Operator.Token, grpcHandler.OperatorMethods),
+			ratelimit.UnaryServerInterceptor(logger, ratelimit.NewMemoryStore(), rateLimits),
+		),
+	)
 	grpcHandler.NewAuthGRPCHandler(grpcServer, logger, authUsecase)
 
 	utilities.RegisterHealthServer(grpcServer)
@@ -5,23 +5,140 @@ import (
 
 	"github.com/caarlos0/env/v11"
 	"github.com/rs/zerolog"
+
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
 )
 
 // AuthServiceConfig contains the configuration for the auth service.
 type AuthServiceConfig struct {
-	Environment string `env:"ENVIRONMENT"`
-	Name        string `env:"SERVICE_NAME"`
-	Address     string `env:"SERVICE_ADDRESS"`
-	Token       TokenConfig
+	Environment   string `env:"ENVIRONMENT"`
+	Name          string `env:"SERVICE_NAME"`
+	Address       string `env:"SERVICE_ADDRESS"`
+	Token         TokenConfig
+	Verification  VerificationConfig
+	PasswordReset PasswordResetConfig
+	MFA           MFAConfig
+	Lockout       LockoutConfig
+	RateLimit     RateLimitConfig
+	OAuth         OAuthConfig
+	MagicLink     MagicLinkConfig
+	WebAuthn      WebAuthnConfig
+	Authorization AuthorizationConfig
+	Operator      OperatorConfig
 }
 
 // TokenConfig contains the configuration for JWT tokens.
 type TokenConfig struct {
-	AccessTokenSecret     string        `env:"ACCESS_TOKEN_SECRET"`
 	RefreshTokenSecret    string        `env:"REFRESH_TOKEN_SECRET"`
 	AccessTokenExpiresIn  time.Duration `env:"ACCESS_TOKEN_EXPIRES_IN"`
 	RefreshTokenExpiresIn time.Duration `env:"REFRESH_TOKEN_EXPIRES_IN"`
 	Issuer                string        `env:"TOKEN_ISSUER"`
+
+	// AccessTokenKeys are read from ACCESS_TOKEN_ALGORITHM, ACCESS_TOKEN_SECRET,
+	// ACCESS_TOKEN_PRIVATE_KEY_FILE and ACCESS_TOKEN_PUBLIC_KEY_FILES.
+	AccessTokenKeys auth.KeySetConfig `envPrefix:"ACCESS_TOKEN_"`
+
+	// With an asymmetric algorithm, access token signing keys are rotated at runtime and
+	// stored encrypted with SigningKeyEncryptionKey. Replaced keys keep verifying tokens for
+	// SigningKeyOverlap, which should be at least AccessTokenExpiresIn.
+	SigningKeyEncryptionKey   string        `env:"SIGNING_KEY_ENCRYPTION_KEY"`
+	SigningKeyOverlap         time.Duration `env:"SIGNING_KEY_OVERLAP"          envDefault:"24h"`
+	SigningKeyRefreshInterval time.Duration `env:"SIGNING_KEY_REFRESH_INTERVAL" envDefault:"1m"`
+
+	// Sessions revoked by other instances are picked up every RevocationSyncInterval.
+	RevocationSyncInterval time.Duration `env:"REVOCATION_SYNC_INTERVAL" envDefault:"5s"`
+
+	// Tokens issued before token types were introduced carry none, and are only accepted when
+	// issued before TokenTypeRequiredAfter. Unless it is set, such tokens are rejected. To keep
+	// them working through the upgrade, set it to the time of the first deployment that issues
+	// typed tokens; it can be removed once RefreshTokenExpiresIn has passed since then.
+	TokenTypeRequiredAfter time.Time `env:"TOKEN_TYPE_REQUIRED_AFTER"`
+}
+
+// VerificationConfig contains the configuration for email verification.
+type VerificationConfig struct {
+	CodeExpiresIn        time.Duration `env:"VERIFICATION_CODE_EXPIRES_IN" envDefault:"15m"`
+	MaxAttempts          int           `env:"VERIFICATION_MAX_ATTEMPTS"    envDefault:"5"`
+	ResendInterval       time.Duration `env:"VERIFICATION_RESEND_INTERVAL" envDefault:"1m"`
+	RequireVerifiedEmail bool          `env:"REQUIRE_VERIFIED_EMAIL"`
+}
+
+// PasswordResetConfig contains the configuration for password recovery.
+type PasswordResetConfig struct {
+	TokenExpiresIn time.Duration `env:"PASSWORD_RESET_TOKEN_EXPIRES_IN" envDefault:"30m"`
+	URL            string        `env:"PASSWORD_RESET_URL,required,notEmpty"`
+}
+
+// MFAConfig contains the configuration for multi-factor authentication.
+type MFAConfig struct {
+	EncryptionKey      string        `env:"MFA_ENCRYPTION_KEY,required,notEmpty"`
+	ChallengeSecret    string        `env:"MFA_CHALLENGE_SECRET,required,notEmpty"`
+	ChallengeExpiresIn time.Duration `env:"MFA_CHALLENGE_EXPIRES_IN" envDefault:"5m"`
+	MaxAttempts        int           `env:"MFA_MAX_ATTEMPTS"         envDefault:"5"`
+	TOTPIssuer         string        `env:"MFA_TOTP_ISSUER"`
+}
+
+// LockoutConfig contains the configuration for locking accounts after repeated failed sign-ins.
+type LockoutConfig struct {
+	AccountThreshold int           `env:"LOCKOUT_ACCOUNT_THRESHOLD" envDefault:"20"`
+	ClientThreshold  int           `env:"LOCKOUT_CLIENT_THRESHOLD"  envDefault:"5"`
+	BaseDelay        time.Duration `env:"LOCKOUT_BASE_DELAY"        envDefault:"1s"`
+	MaxDelay         time.Duration `env:"LOCKOUT_MAX_DELAY"         envDefault:"1m"`
+	Duration         time.Duration `env:"LOCKOUT_DURATION"          envDefault:"15m"`
+	FailureWindow    time.Duration `env:"LOCKOUT_FAILURE_WINDOW"    envDefault:"1h"`
+}
+
+// RateLimitConfig contains the per-RPC rate limits, such as "SignIn:10/1m,SignUp:5/1h".
+// RPCs that RATE_LIMITS leaves out keep their default limit.
+type RateLimitConfig struct {
+	Limits map[string]string `env:"RATE_LIMITS"`
+}
+
+// defaultRateLimits are the limits of the RPCs that check credentials, codes or tokens, or send
+// emails.
+var defaultRateLimits = map[string]string{
+	"SignIn":             "10/1m",
+	"SignUp":             "5/1h",
+	"RequestMagicLink":   "5/15m",
+	"RedeemRecoveryCode": "5/15m",
+	"VerifyMFA":          "10/5m",
+	"VerifyEmail":        "10/15m",
+	"ResetPassword":      "5/15m",
+	"ConsumeMagicLink":   "10/15m",
+	"FinishPasskeyLogin": "10/1m",
+}
+
+// OAuthConfig contains the configuration for signing in with OAuth providers.
+type OAuthConfig struct {
+	StateExpiresIn time.Duration `env:"OAUTH_STATE_EXPIRES_IN" envDefault:"10m"`
+}
+
+// MagicLinkConfig contains the configuration for passwordless sign-in with emailed links.
+type MagicLinkConfig struct {
+	Secret    string        `env:"MAGIC_LINK_SECRET,required,notEmpty"`
+	ExpiresIn time.Duration `env:"MAGIC_LINK_EXPIRES_IN" envDefault:"15m"`
+	URL       string        `env:"MAGIC_LINK_URL,required,notEmpty"`
+}
+
+// WebAuthnConfig contains the configuration for signing in with passkeys.
+// Passkeys are disabled when no relying party ID is set.
+type WebAuthnConfig struct {
+	RPID               string        `env:"WEBAUTHN_RP_ID"`
+	RPDisplayName      string        `env:"WEBAUTHN_RP_DISPLAY_NAME"`
+	RPOrigins          []string      `env:"WEBAUTHN_RP_ORIGINS"`
+	ChallengeExpiresIn time.Duration `env:"WEBAUTHN_CHALLENGE_EXPIRES_IN" envDefault:"5m"`
+}
+
+// AuthorizationConfig contains the scopes granted to each role, such as "user:profile,admin:profile users",
+// with the scopes of a role separated by spaces.
+type AuthorizationConfig struct {
+	RoleScopes map[string]string `env:"ROLE_SCOPES" envDefault:"user:profile sessions,admin:profile sessions users"`
+}
+
+// OperatorConfig contains the configuration for the RPCs meant for operators, which must be
+// called with the operator token. They are disabled when no token is set.
+type OperatorConfig struct {
+	Token string `env:"OPERATOR_TOKEN"`
 }
 
 // NewAuthServiceConfig creates a new AuthServiceConfig instance from environment variables.
@@ -31,5 +148,14 @@ func NewAuthServiceConfig(logger *zerolog.Logger) *AuthServiceConfig {
 		logger.Fatal().Err(err).Msg("failed to parse environment variables")
 	}
 
+	if cfg.RateLimit.Limits == nil {
+		cfg.RateLimit.Limits = make(map[string]string, len(defaultRateLimits))
+	}
+	for method, limit := range defaultRateLimits {
+		if _, ok := cfg.RateLimit.Limits[method]; !ok {
+			cfg.RateLimit.Limits[method] = limit
+		}
+	}
+
 	return &cfg
 }
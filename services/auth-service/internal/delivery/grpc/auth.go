@@ -3,17 +3,34 @@ package grpc
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
 	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/usecase"
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
+	"github.com/vasapolrittideah/optimize-api/shared/contract"
 	authpbv1 "github.com/vasapolrittideah/optimize-api/shared/protos/auth/v1"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
+	"github.com/vasapolrittideah/optimize-api/shared/utilities"
 )
 
+// OperatorMethods are the RPCs that only operators may call, with the operator token.
+var OperatorMethods = []string{
+	"UnlockAccount",
+	"RevokeSession",
+	"ListRevokedSessions",
+	"ListSigningKeys",
+	"StageSigningKey",
+	"PromoteSigningKey",
+	"RetireSigningKey",
+}
+
 type authGRPCHandler struct {
 	authpbv1.UnimplementedAuthServiceServer
 
@@ -41,21 +58,36 @@ func (h *authGRPCHandler) SignIn(ctx context.Context, req *authpbv1.SignInReques
 		Password: req.GetPassword(),
 	}
 
-	tokens, err := h.authUsecase.SignIn(ctx, params)
+	result, err := h.authUsecase.SignIn(ctx, params)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to sign in")
 
 		switch {
-		case errors.Is(err, usecase.ErrInvalidCredentials):
+		// A locked account answers like a wrong password, so that a lockout does not
+		// reveal that the email address is registered.
+		case errors.Is(err, usecase.ErrInvalidCredentials), errors.Is(err, usecase.ErrAccountLocked):
 			return nil, status.Errorf(codes.Unauthenticated, "invalid credentials")
+		case errors.Is(err, usecase.ErrEmailNotVerified):
+			return nil, utilities.NewGRPCErrorWithReason(
+				codes.PermissionDenied,
+				contract.ErrorCodeEmailNotVerified,
+				"email not verified",
+			)
 		default:
 			return nil, status.Errorf(codes.Internal, "something went wrong")
 		}
 	}
 
+	if result.MFAToken != "" {
+		return &authpbv1.SignInResponse{
+			MfaRequired: true,
+			MfaToken:    result.MFAToken,
+		}, nil
+	}
+
 	return &authpbv1.SignInResponse{
-		AccessToken:  tokens.AccessToken,
-		RefreshToken: tokens.RefreshToken,
+		AccessToken:  result.Tokens.AccessToken,
+		RefreshToken: result.Tokens.RefreshToken,
 	}, nil
 }
 
@@ -66,20 +98,1094 @@ func (h *authGRPCHandler) SignUp(ctx context.Context, req *authpbv1.SignUpReques
 		FullName: req.GetFullName(),
 	}
 
-	tokens, err := h.authUsecase.SignUp(ctx, params)
+	result, err := h.authUsecase.SignUp(ctx, params)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to sign up")
 
 		switch {
 		case errors.Is(err, usecase.ErrUserAlreadyExists):
 			return nil, status.Errorf(codes.AlreadyExists, "user already exists")
+		case errors.Is(err, security.ErrPasswordPolicy), errors.Is(err, usecase.ErrPasswordCompromised):
+			return nil, passwordValidationError("password", err)
 		default:
 			return nil, status.Errorf(codes.Internal, "something went wrong")
 		}
 	}
 
+	if result.VerificationRequired {
+		return &authpbv1.SignUpResponse{VerificationRequired: true}, nil
+	}
+
 	return &authpbv1.SignUpResponse{
+		AccessToken:  result.Tokens.AccessToken,
+		RefreshToken: result.Tokens.RefreshToken,
+	}, nil
+}
+
+func (h *authGRPCHandler) RefreshTokens(
+	ctx context.Context,
+	req *authpbv1.RefreshTokensRequest,
+) (*authpbv1.RefreshTokensResponse, error) {
+	params := domain.RefreshTokensParams{
+		RefreshToken: req.GetRefreshToken(),
+	}
+
+	tokens, err := h.authUsecase.RefreshTokens(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to refresh tokens")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid refresh token")
+		case errors.Is(err, usecase.ErrSessionRevoked):
+			return nil, status.Errorf(codes.Unauthenticated, "session has been revoked")
+		case errors.Is(err, usecase.ErrRefreshTokenReused):
+			return nil, utilities.NewGRPCErrorWithReason(
+				codes.Unauthenticated,
+				contract.ErrorCodeRefreshTokenReused,
+				"refresh token reuse detected",
+			)
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.RefreshTokensResponse{
 		AccessToken:  tokens.AccessToken,
 		RefreshToken: tokens.RefreshToken,
 	}, nil
 }
+
+func (h *authGRPCHandler) SignOut(
+	ctx context.Context,
+	req *authpbv1.SignOutRequest,
+) (*authpbv1.SignOutResponse, error) {
+	params := domain.SignOutParams{
+		AccessToken: req.GetAccessToken(),
+	}
+
+	if err := h.authUsecase.SignOut(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to sign out")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.SignOutResponse{}, nil
+}
+
+func (h *authGRPCHandler) SignOutAll(
+	ctx context.Context,
+	req *authpbv1.SignOutAllRequest,
+) (*authpbv1.SignOutAllResponse, error) {
+	params := domain.SignOutAllParams{
+		AccessToken: req.GetAccessToken(),
+	}
+
+	if err := h.authUsecase.SignOutAll(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to sign out of all sessions")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.SignOutAllResponse{}, nil
+}
+
+func (h *authGRPCHandler) ListSessions(
+	ctx context.Context,
+	req *authpbv1.ListSessionsRequest,
+) (*authpbv1.ListSessionsResponse, error) {
+	params := domain.ListSessionsParams{
+		AccessToken: req.GetAccessToken(),
+	}
+
+	sessions, err := h.authUsecase.ListSessions(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list sessions")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		case errors.Is(err, usecase.ErrSessionRevoked):
+			return nil, status.Errorf(codes.Unauthenticated, "session has been revoked")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	pbSessions := make([]*authpbv1.Session, 0, len(sessions))
+	for _, session := range sessions {
+		pbSessions = append(pbSessions, &authpbv1.Session{
+			Id:         session.ID,
+			IpAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  timestamppb.New(session.CreatedAt),
+			LastUsedAt: timestamppb.New(session.LastUsedAt),
+			Current:    session.Current,
+		})
+	}
+
+	return &authpbv1.ListSessionsResponse{
+		Sessions: pbSessions,
+	}, nil
+}
+
+func (h *authGRPCHandler) VerifyEmail(
+	ctx context.Context,
+	req *authpbv1.VerifyEmailRequest,
+) (*authpbv1.VerifyEmailResponse, error) {
+	params := domain.VerifyEmailParams{
+		Email: req.GetEmail(),
+		Code:  req.GetCode(),
+	}
+
+	if err := h.authUsecase.VerifyEmail(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to verify email")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidVerificationCode):
+			return nil, status.Errorf(codes.InvalidArgument, "invalid or expired verification code")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.VerifyEmailResponse{}, nil
+}
+
+func (h *authGRPCHandler) ResendVerification(
+	ctx context.Context,
+	req *authpbv1.ResendVerificationRequest,
+) (*authpbv1.ResendVerificationResponse, error) {
+	params := domain.ResendVerificationParams{
+		Email: req.GetEmail(),
+	}
+
+	if err := h.authUsecase.ResendVerification(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to resend verification code")
+		return nil, status.Errorf(codes.Internal, "something went wrong")
+	}
+
+	return &authpbv1.ResendVerificationResponse{}, nil
+}
+
+func (h *authGRPCHandler) RequestPasswordReset(
+	ctx context.Context,
+	req *authpbv1.RequestPasswordResetRequest,
+) (*authpbv1.RequestPasswordResetResponse, error) {
+	params := domain.RequestPasswordResetParams{
+		Email: req.GetEmail(),
+	}
+
+	if err := h.authUsecase.RequestPasswordReset(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to request password reset")
+		return nil, status.Errorf(codes.Internal, "something went wrong")
+	}
+
+	return &authpbv1.RequestPasswordResetResponse{}, nil
+}
+
+func (h *authGRPCHandler) ResetPassword(
+	ctx context.Context,
+	req *authpbv1.ResetPasswordRequest,
+) (*authpbv1.ResetPasswordResponse, error) {
+	params := domain.ResetPasswordParams{
+		Token:       req.GetToken(),
+		NewPassword: req.GetNewPassword(),
+	}
+
+	if err := h.authUsecase.ResetPassword(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to reset password")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidResetToken):
+			return nil, status.Errorf(codes.InvalidArgument, "invalid or expired password reset token")
+		case errors.Is(err, security.ErrPasswordPolicy), errors.Is(err, usecase.ErrPasswordCompromised):
+			return nil, passwordValidationError("new_password", err)
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.ResetPasswordResponse{}, nil
+}
+
+func (h *authGRPCHandler) ChangePassword(
+	ctx context.Context,
+	req *authpbv1.ChangePasswordRequest,
+) (*authpbv1.ChangePasswordResponse, error) {
+	params := domain.ChangePasswordParams{
+		AccessToken:         req.GetAccessToken(),
+		CurrentPassword:     req.GetCurrentPassword(),
+		NewPassword:         req.GetNewPassword(),
+		RevokeOtherSessions: req.GetRevokeOtherSessions(),
+	}
+
+	if err := h.authUsecase.ChangePassword(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to change password")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		case errors.Is(err, usecase.ErrSessionRevoked):
+			return nil, status.Errorf(codes.Unauthenticated, "session has been revoked")
+		case errors.Is(err, usecase.ErrIncorrectPassword):
+			return nil, status.Errorf(codes.InvalidArgument, "incorrect current password")
+		case errors.Is(err, security.ErrPasswordPolicy), errors.Is(err, usecase.ErrPasswordCompromised):
+			return nil, passwordValidationError("new_password", err)
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.ChangePasswordResponse{}, nil
+}
+
+func (h *authGRPCHandler) EnrollTOTP(
+	ctx context.Context,
+	req *authpbv1.EnrollTOTPRequest,
+) (*authpbv1.EnrollTOTPResponse, error) {
+	params := domain.EnrollTOTPParams{
+		AccessToken: req.GetAccessToken(),
+	}
+
+	enrollment, err := h.authUsecase.EnrollTOTP(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to enroll TOTP")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		case errors.Is(err, usecase.ErrSessionRevoked):
+			return nil, status.Errorf(codes.Unauthenticated, "session has been revoked")
+		case errors.Is(err, usecase.ErrMFAAlreadyEnabled):
+			return nil, status.Errorf(codes.FailedPrecondition, "multi-factor authentication already enabled")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.EnrollTOTPResponse{
+		Secret: enrollment.Secret,
+		Uri:    enrollment.URI,
+	}, nil
+}
+
+func (h *authGRPCHandler) ConfirmTOTP(
+	ctx context.Context,
+	req *authpbv1.ConfirmTOTPRequest,
+) (*authpbv1.ConfirmTOTPResponse, error) {
+	params := domain.ConfirmTOTPParams{
+		AccessToken: req.GetAccessToken(),
+		Code:        req.GetCode(),
+	}
+
+	if err := h.authUsecase.ConfirmTOTP(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to confirm TOTP")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		case errors.Is(err, usecase.ErrSessionRevoked):
+			return nil, status.Errorf(codes.Unauthenticated, "session has been revoked")
+		case errors.Is(err, usecase.ErrMFAAlreadyEnabled):
+			return nil, status.Errorf(codes.FailedPrecondition, "multi-factor authentication already enabled")
+		case errors.Is(err, usecase.ErrMFANotEnrolled):
+			return nil, status.Errorf(codes.FailedPrecondition, "multi-factor authentication not enrolled")
+		case errors.Is(err, usecase.ErrInvalidMFACode):
+			return nil, status.Errorf(codes.InvalidArgument, "invalid authentication code")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.ConfirmTOTPResponse{}, nil
+}
+
+func (h *authGRPCHandler) DisableTOTP(
+	ctx context.Context,
+	req *authpbv1.DisableTOTPRequest,
+) (*authpbv1.DisableTOTPResponse, error) {
+	params := domain.DisableTOTPParams{
+		AccessToken: req.GetAccessToken(),
+		Code:        req.GetCode(),
+	}
+
+	if err := h.authUsecase.DisableTOTP(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to disable TOTP")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		case errors.Is(err, usecase.ErrSessionRevoked):
+			return nil, status.Errorf(codes.Unauthenticated, "session has been revoked")
+		case errors.Is(err, usecase.ErrMFANotEnrolled):
+			return nil, status.Errorf(codes.FailedPrecondition, "multi-factor authentication not enrolled")
+		case errors.Is(err, usecase.ErrInvalidMFACode):
+			return nil, status.Errorf(codes.InvalidArgument, "invalid authentication code")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.DisableTOTPResponse{}, nil
+}
+
+func (h *authGRPCHandler) VerifyMFA(
+	ctx context.Context,
+	req *authpbv1.VerifyMFARequest,
+) (*authpbv1.VerifyMFAResponse, error) {
+	params := domain.VerifyMFAParams{
+		MFAToken: req.GetMfaToken(),
+		Code:     req.GetCode(),
+	}
+
+	tokens, err := h.authUsecase.VerifyMFA(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to verify MFA")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidMFAToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid or expired MFA token")
+		case errors.Is(err, usecase.ErrInvalidMFACode):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid authentication code")
+		case errors.Is(err, usecase.ErrTooManyMFAAttempts):
+			return nil, status.Errorf(codes.ResourceExhausted, "too many attempts, sign in again")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.VerifyMFAResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (h *authGRPCHandler) GenerateRecoveryCodes(
+	ctx context.Context,
+	req *authpbv1.GenerateRecoveryCodesRequest,
+) (*authpbv1.GenerateRecoveryCodesResponse, error) {
+	params := domain.GenerateRecoveryCodesParams{
+		AccessToken: req.GetAccessToken(),
+	}
+
+	recoveryCodes, err := h.authUsecase.GenerateRecoveryCodes(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to generate recovery codes")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		case errors.Is(err, usecase.ErrSessionRevoked):
+			return nil, status.Errorf(codes.Unauthenticated, "session has been revoked")
+		case errors.Is(err, usecase.ErrMFANotEnrolled):
+			return nil, status.Errorf(codes.FailedPrecondition, "multi-factor authentication not enrolled")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.GenerateRecoveryCodesResponse{
+		Codes: recoveryCodes,
+	}, nil
+}
+
+func (h *authGRPCHandler) RedeemRecoveryCode(
+	ctx context.Context,
+	req *authpbv1.RedeemRecoveryCodeRequest,
+) (*authpbv1.RedeemRecoveryCodeResponse, error) {
+	params := domain.RedeemRecoveryCodeParams{
+		Email: req.GetEmail(),
+		Code:  req.GetCode(),
+	}
+
+	redemption, err := h.authUsecase.RedeemRecoveryCode(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to redeem recovery code")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidRecoveryCode):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid recovery code")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.RedeemRecoveryCodeResponse{
+		AccessToken:            redemption.Tokens.AccessToken,
+		RefreshToken:           redemption.Tokens.RefreshToken,
+		PasswordChangeRequired: redemption.PasswordChangeRequired,
+	}, nil
+}
+
+func (h *authGRPCHandler) CountRecoveryCodes(
+	ctx context.Context,
+	req *authpbv1.CountRecoveryCodesRequest,
+) (*authpbv1.CountRecoveryCodesResponse, error) {
+	params := domain.CountRecoveryCodesParams{
+		AccessToken: req.GetAccessToken(),
+	}
+
+	remaining, err := h.authUsecase.CountRecoveryCodes(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to count recovery codes")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		case errors.Is(err, usecase.ErrSessionRevoked):
+			return nil, status.Errorf(codes.Unauthenticated, "session has been revoked")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.CountRecoveryCodesResponse{
+		RemainingCodes: int32(remaining),
+	}, nil
+}
+
+func (h *authGRPCHandler) UnlockAccount(
+	ctx context.Context,
+	req *authpbv1.UnlockAccountRequest,
+) (*authpbv1.UnlockAccountResponse, error) {
+	params := domain.UnlockAccountParams{
+		UserID: req.GetUserId(),
+	}
+
+	if err := h.authUsecase.UnlockAccount(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to unlock account")
+
+		switch {
+		case errors.Is(err, usecase.ErrUserNotFound):
+			return nil, status.Errorf(codes.NotFound, "user not found")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.UnlockAccountResponse{}, nil
+}
+
+func (h *authGRPCHandler) BeginOAuth(
+	ctx context.Context,
+	req *authpbv1.BeginOAuthRequest,
+) (*authpbv1.BeginOAuthResponse, error) {
+	params := domain.BeginOAuthParams{
+		Provider: req.GetProvider(),
+	}
+
+	authorization, err := h.authUsecase.BeginOAuth(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to begin OAuth sign-in")
+
+		switch {
+		case errors.Is(err, usecase.ErrUnknownOAuthProvider):
+			return nil, status.Errorf(codes.NotFound, "unknown OAuth provider")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.BeginOAuthResponse{
+		AuthorizationUrl: authorization.URL,
+		State:            authorization.State,
+	}, nil
+}
+
+func (h *authGRPCHandler) CompleteOAuth(
+	ctx context.Context,
+	req *authpbv1.CompleteOAuthRequest,
+) (*authpbv1.CompleteOAuthResponse, error) {
+	params := domain.CompleteOAuthParams{
+		Provider: req.GetProvider(),
+		State:    req.GetState(),
+		Code:     req.GetCode(),
+	}
+
+	result, err := h.authUsecase.CompleteOAuth(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to complete OAuth sign-in")
+
+		switch {
+		case errors.Is(err, usecase.ErrUnknownOAuthProvider):
+			return nil, status.Errorf(codes.NotFound, "unknown OAuth provider")
+		case errors.Is(err, usecase.ErrInvalidOAuthState):
+			return nil, status.Errorf(codes.InvalidArgument, "invalid or expired OAuth state")
+		case errors.Is(err, usecase.ErrOAuthFailed):
+			return nil, status.Errorf(codes.Unauthenticated, "failed to sign in with OAuth provider")
+		case errors.Is(err, usecase.ErrOAuthEmailRequired):
+			return nil, status.Errorf(codes.FailedPrecondition, "OAuth provider did not return an email address")
+		case errors.Is(err, usecase.ErrUserAlreadyExists):
+			return nil, status.Errorf(codes.AlreadyExists, "user already exists")
+		case errors.Is(err, usecase.ErrIdentityAlreadyLinked):
+			return nil, status.Errorf(codes.AlreadyExists, "identity is already linked to another user")
+		case errors.Is(err, usecase.ErrEmailNotVerified):
+			return nil, utilities.NewGRPCErrorWithReason(
+				codes.PermissionDenied,
+				contract.ErrorCodeEmailNotVerified,
+				"email not verified",
+			)
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	if result.IdentityLinked {
+		return &authpbv1.CompleteOAuthResponse{
+			IdentityLinked: true,
+		}, nil
+	}
+
+	if result.MFAToken != "" {
+		return &authpbv1.CompleteOAuthResponse{
+			MfaRequired: true,
+			MfaToken:    result.MFAToken,
+		}, nil
+	}
+
+	return &authpbv1.CompleteOAuthResponse{
+		AccessToken:  result.Tokens.AccessToken,
+		RefreshToken: result.Tokens.RefreshToken,
+	}, nil
+}
+
+func (h *authGRPCHandler) ListIdentities(
+	ctx context.Context,
+	req *authpbv1.ListIdentitiesRequest,
+) (*authpbv1.ListIdentitiesResponse, error) {
+	params := domain.ListIdentitiesParams{
+		AccessToken: req.GetAccessToken(),
+	}
+
+	identities, err := h.authUsecase.ListIdentities(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list identities")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		case errors.Is(err, usecase.ErrSessionRevoked):
+			return nil, status.Errorf(codes.Unauthenticated, "session has been revoked")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	pbIdentities := make([]*authpbv1.Identity, 0, len(identities))
+	for _, identity := range identities {
+		pbIdentity := &authpbv1.Identity{
+			Id:        identity.ID.Hex(),
+			Provider:  identity.Provider,
+			Email:     identity.Email,
+			CreatedAt: timestamppb.New(identity.CreatedAt),
+		}
+		if !identity.LastLoginAt.IsZero() {
+			pbIdentity.LastLoginAt = timestamppb.New(identity.LastLoginAt)
+		}
+
+		pbIdentities = append(pbIdentities, pbIdentity)
+	}
+
+	return &authpbv1.ListIdentitiesResponse{
+		Identities: pbIdentities,
+	}, nil
+}
+
+func (h *authGRPCHandler) LinkIdentity(
+	ctx context.Context,
+	req *authpbv1.LinkIdentityRequest,
+) (*authpbv1.LinkIdentityResponse, error) {
+	params := domain.LinkIdentityParams{
+		AccessToken: req.GetAccessToken(),
+		Provider:    req.GetProvider(),
+	}
+
+	authorization, err := h.authUsecase.LinkIdentity(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to link identity")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		case errors.Is(err, usecase.ErrSessionRevoked):
+			return nil, status.Errorf(codes.Unauthenticated, "session has been revoked")
+		case errors.Is(err, usecase.ErrUnknownOAuthProvider):
+			return nil, status.Errorf(codes.NotFound, "unknown OAuth provider")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.LinkIdentityResponse{
+		AuthorizationUrl: authorization.URL,
+		State:            authorization.State,
+	}, nil
+}
+
+func (h *authGRPCHandler) UnlinkIdentity(
+	ctx context.Context,
+	req *authpbv1.UnlinkIdentityRequest,
+) (*authpbv1.UnlinkIdentityResponse, error) {
+	params := domain.UnlinkIdentityParams{
+		AccessToken: req.GetAccessToken(),
+		IdentityID:  req.GetIdentityId(),
+	}
+
+	if err := h.authUsecase.UnlinkIdentity(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to unlink identity")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		case errors.Is(err, usecase.ErrSessionRevoked):
+			return nil, status.Errorf(codes.Unauthenticated, "session has been revoked")
+		case errors.Is(err, usecase.ErrIdentityNotFound):
+			return nil, status.Errorf(codes.NotFound, "identity not found")
+		case errors.Is(err, usecase.ErrIdentityNotUnlinkable):
+			return nil, status.Errorf(codes.FailedPrecondition, "email identity cannot be unlinked")
+		case errors.Is(err, usecase.ErrLastLoginMethod):
+			return nil, status.Errorf(codes.FailedPrecondition, "cannot remove the last login method")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.UnlinkIdentityResponse{}, nil
+}
+
+func (h *authGRPCHandler) RequestMagicLink(
+	ctx context.Context,
+	req *authpbv1.RequestMagicLinkRequest,
+) (*authpbv1.RequestMagicLinkResponse, error) {
+	params := domain.RequestMagicLinkParams{
+		Email:      req.GetEmail(),
+		CreateUser: req.GetCreateUser(),
+	}
+
+	if err := h.authUsecase.RequestMagicLink(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to request magic link")
+		return nil, status.Errorf(codes.Internal, "something went wrong")
+	}
+
+	return &authpbv1.RequestMagicLinkResponse{}, nil
+}
+
+func (h *authGRPCHandler) ConsumeMagicLink(
+	ctx context.Context,
+	req *authpbv1.ConsumeMagicLinkRequest,
+) (*authpbv1.ConsumeMagicLinkResponse, error) {
+	params := domain.ConsumeMagicLinkParams{
+		Token: req.GetToken(),
+	}
+
+	result, err := h.authUsecase.ConsumeMagicLink(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to consume magic link")
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidMagicLink):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid or expired magic link")
+		case errors.Is(err, usecase.ErrUserAlreadyExists):
+			return nil, status.Errorf(codes.AlreadyExists, "user already exists")
+		case errors.Is(err, usecase.ErrIdentityAlreadyLinked):
+			return nil, status.Errorf(codes.AlreadyExists, "identity is already linked to another user")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	if result.MFAToken != "" {
+		return &authpbv1.ConsumeMagicLinkResponse{
+			MfaRequired: true,
+			MfaToken:    result.MFAToken,
+		}, nil
+	}
+
+	return &authpbv1.ConsumeMagicLinkResponse{
+		AccessToken:  result.Tokens.AccessToken,
+		RefreshToken: result.Tokens.RefreshToken,
+	}, nil
+}
+
+func (h *authGRPCHandler) BeginPasskeyRegistration(
+	ctx context.Context,
+	req *authpbv1.BeginPasskeyRegistrationRequest,
+) (*authpbv1.BeginPasskeyRegistrationResponse, error) {
+	params := domain.BeginPasskeyRegistrationParams{
+		AccessToken: req.GetAccessToken(),
+	}
+
+	challenge, err := h.authUsecase.BeginPasskeyRegistration(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to begin passkey registration")
+
+		switch {
+		case errors.Is(err, usecase.ErrPasskeysDisabled):
+			return nil, status.Errorf(codes.FailedPrecondition, "passkeys are not enabled")
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		case errors.Is(err, usecase.ErrSessionRevoked):
+			return nil, status.Errorf(codes.Unauthenticated, "session has been revoked")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.BeginPasskeyRegistrationResponse{
+		ChallengeId: challenge.ChallengeID,
+		Options:     challenge.Options,
+	}, nil
+}
+
+func (h *authGRPCHandler) FinishPasskeyRegistration(
+	ctx context.Context,
+	req *authpbv1.FinishPasskeyRegistrationRequest,
+) (*authpbv1.FinishPasskeyRegistrationResponse, error) {
+	params := domain.FinishPasskeyRegistrationParams{
+		AccessToken: req.GetAccessToken(),
+		ChallengeID: req.GetChallengeId(),
+		Credential:  req.GetCredential(),
+		Name:        req.GetName(),
+	}
+
+	credential, err := h.authUsecase.FinishPasskeyRegistration(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to finish passkey registration")
+
+		switch {
+		case errors.Is(err, usecase.ErrPasskeysDisabled):
+			return nil, status.Errorf(codes.FailedPrecondition, "passkeys are not enabled")
+		case errors.Is(err, usecase.ErrInvalidToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid access token")
+		case errors.Is(err, usecase.ErrSessionRevoked):
+			return nil, status.Errorf(codes.Unauthenticated, "session has been revoked")
+		case errors.Is(err, usecase.ErrInvalidPasskeyChallenge):
+			return nil, status.Errorf(codes.InvalidArgument, "invalid or expired passkey challenge")
+		case errors.Is(err, usecase.ErrInvalidPasskey):
+			return nil, status.Errorf(codes.InvalidArgument, "invalid passkey")
+		case errors.Is(err, usecase.ErrPasskeyAlreadyRegistered):
+			return nil, status.Errorf(codes.AlreadyExists, "passkey already registered")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.FinishPasskeyRegistrationResponse{
+		Id:        credential.ID.Hex(),
+		Name:      credential.Name,
+		CreatedAt: timestamppb.New(credential.CreatedAt),
+	}, nil
+}
+
+func (h *authGRPCHandler) BeginPasskeyLogin(
+	ctx context.Context,
+	req *authpbv1.BeginPasskeyLoginRequest,
+) (*authpbv1.BeginPasskeyLoginResponse, error) {
+	params := domain.BeginPasskeyLoginParams{
+		MFAToken: req.GetMfaToken(),
+	}
+
+	challenge, err := h.authUsecase.BeginPasskeyLogin(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to begin passkey login")
+
+		switch {
+		case errors.Is(err, usecase.ErrPasskeysDisabled):
+			return nil, status.Errorf(codes.FailedPrecondition, "passkeys are not enabled")
+		case errors.Is(err, usecase.ErrInvalidMFAToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid or expired MFA token")
+		case errors.Is(err, usecase.ErrMFANotEnrolled):
+			return nil, status.Errorf(codes.FailedPrecondition, "no passkey registered")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.BeginPasskeyLoginResponse{
+		ChallengeId: challenge.ChallengeID,
+		Options:     challenge.Options,
+	}, nil
+}
+
+func (h *authGRPCHandler) FinishPasskeyLogin(
+	ctx context.Context,
+	req *authpbv1.FinishPasskeyLoginRequest,
+) (*authpbv1.FinishPasskeyLoginResponse, error) {
+	params := domain.FinishPasskeyLoginParams{
+		ChallengeID: req.GetChallengeId(),
+		Credential:  req.GetCredential(),
+	}
+
+	tokens, err := h.authUsecase.FinishPasskeyLogin(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to finish passkey login")
+
+		switch {
+		case errors.Is(err, usecase.ErrPasskeysDisabled):
+			return nil, status.Errorf(codes.FailedPrecondition, "passkeys are not enabled")
+		case errors.Is(err, usecase.ErrInvalidPasskeyChallenge):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid or expired passkey challenge")
+		case errors.Is(err, usecase.ErrInvalidPasskey):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid passkey")
+		case errors.Is(err, usecase.ErrInvalidMFAToken):
+			return nil, status.Errorf(codes.Unauthenticated, "invalid or expired MFA token")
+		case errors.Is(err, usecase.ErrEmailNotVerified):
+			return nil, utilities.NewGRPCErrorWithReason(
+				codes.PermissionDenied,
+				contract.ErrorCodeEmailNotVerified,
+				"email not verified",
+			)
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.FinishPasskeyLoginResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (h *authGRPCHandler) IntrospectToken(
+	ctx context.Context,
+	req *authpbv1.IntrospectTokenRequest,
+) (*authpbv1.IntrospectTokenResponse, error) {
+	params := domain.IntrospectTokenParams{
+		Token: req.GetToken(),
+	}
+
+	introspection, err := h.authUsecase.IntrospectToken(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to introspect token")
+		return nil, status.Errorf(codes.Internal, "something went wrong")
+	}
+
+	if !introspection.Active {
+		return &authpbv1.IntrospectTokenResponse{}, nil
+	}
+
+	return &authpbv1.IntrospectTokenResponse{
+		Active:    true,
+		Sub:       introspection.UserID,
+		SessionId: introspection.SessionID,
+		Exp:       timestamppb.New(introspection.ExpiresAt),
+		Iat:       timestamppb.New(introspection.IssuedAt),
+		Scopes:    introspection.Scopes,
+	}, nil
+}
+
+func (h *authGRPCHandler) ListRevokedSessions(
+	ctx context.Context,
+	req *authpbv1.ListRevokedSessionsRequest,
+) (*authpbv1.ListRevokedSessionsResponse, error) {
+	params := domain.ListRevokedSessionsParams{}
+	if req.GetSince() != nil {
+		params.Since = req.GetSince().AsTime()
+	}
+
+	revokedSessions, err := h.authUsecase.ListRevokedSessions(ctx, params)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list revoked sessions")
+		return nil, status.Errorf(codes.Internal, "something went wrong")
+	}
+
+	pbRevokedSessions := make([]*authpbv1.RevokedSession, 0, len(revokedSessions))
+	for _, revokedSession := range revokedSessions {
+		pbRevokedSessions = append(pbRevokedSessions, &authpbv1.RevokedSession{
+			SessionId: revokedSession.SessionID,
+			ExpiresAt: timestamppb.New(revokedSession.ExpiresAt),
+			RevokedAt: timestamppb.New(revokedSession.RevokedAt),
+		})
+	}
+
+	return &authpbv1.ListRevokedSessionsResponse{
+		RevokedSessions: pbRevokedSessions,
+	}, nil
+}
+
+func (h *authGRPCHandler) RevokeSession(
+	ctx context.Context,
+	req *authpbv1.RevokeSessionRequest,
+) (*authpbv1.RevokeSessionResponse, error) {
+	params := domain.RevokeSessionParams{
+		SessionID: req.GetSessionId(),
+	}
+
+	if err := h.authUsecase.RevokeSession(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to revoke session")
+
+		switch {
+		case errors.Is(err, usecase.ErrSessionNotFound):
+			return nil, status.Errorf(codes.NotFound, "session not found")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.RevokeSessionResponse{}, nil
+}
+
+func (h *authGRPCHandler) GetJWKS(
+	ctx context.Context,
+	_ *authpbv1.GetJWKSRequest,
+) (*authpbv1.GetJWKSResponse, error) {
+	jwks, err := h.authUsecase.GetJWKS(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to get JWKS")
+		return nil, status.Errorf(codes.Internal, "something went wrong")
+	}
+
+	pbKeys := make([]*authpbv1.JSONWebKey, 0, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		pbKeys = append(pbKeys, &authpbv1.JSONWebKey{
+			Kty: jwk.KeyType,
+			Kid: jwk.KeyID,
+			Use: jwk.Use,
+			Alg: jwk.Algorithm,
+			N:   jwk.N,
+			E:   jwk.E,
+			Crv: jwk.Curve,
+			X:   jwk.X,
+			Y:   jwk.Y,
+		})
+	}
+
+	return &authpbv1.GetJWKSResponse{
+		Keys: pbKeys,
+	}, nil
+}
+
+func (h *authGRPCHandler) ListSigningKeys(
+	ctx context.Context,
+	_ *authpbv1.ListSigningKeysRequest,
+) (*authpbv1.ListSigningKeysResponse, error) {
+	keys, err := h.authUsecase.ListSigningKeys(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list signing keys")
+		return nil, status.Errorf(codes.Internal, "something went wrong")
+	}
+
+	pbKeys := make([]*authpbv1.SigningKey, 0, len(keys))
+	for _, key := range keys {
+		pbKeys = append(pbKeys, newSigningKeyResponse(&key))
+	}
+
+	return &authpbv1.ListSigningKeysResponse{
+		SigningKeys: pbKeys,
+	}, nil
+}
+
+func (h *authGRPCHandler) StageSigningKey(
+	ctx context.Context,
+	_ *authpbv1.StageSigningKeyRequest,
+) (*authpbv1.StageSigningKeyResponse, error) {
+	key, err := h.authUsecase.StageSigningKey(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to stage signing key")
+
+		switch {
+		case errors.Is(err, usecase.ErrKeyRotationDisabled):
+			return nil, status.Errorf(codes.FailedPrecondition, "signing key rotation requires an asymmetric algorithm")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.StageSigningKeyResponse{
+		SigningKey: newSigningKeyResponse(key),
+	}, nil
+}
+
+func (h *authGRPCHandler) PromoteSigningKey(
+	ctx context.Context,
+	req *authpbv1.PromoteSigningKeyRequest,
+) (*authpbv1.PromoteSigningKeyResponse, error) {
+	params := domain.PromoteSigningKeyParams{
+		KeyID: req.GetKeyId(),
+	}
+
+	if err := h.authUsecase.PromoteSigningKey(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to promote signing key")
+
+		switch {
+		case errors.Is(err, usecase.ErrSigningKeyNotFound):
+			return nil, status.Errorf(codes.NotFound, "signing key not found")
+		case errors.Is(err, usecase.ErrSigningKeyNotStaged):
+			return nil, status.Errorf(codes.FailedPrecondition, "signing key is not staged")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.PromoteSigningKeyResponse{}, nil
+}
+
+func (h *authGRPCHandler) RetireSigningKey(
+	ctx context.Context,
+	req *authpbv1.RetireSigningKeyRequest,
+) (*authpbv1.RetireSigningKeyResponse, error) {
+	params := domain.RetireSigningKeyParams{
+		KeyID: req.GetKeyId(),
+	}
+
+	if err := h.authUsecase.RetireSigningKey(ctx, params); err != nil {
+		h.logger.Error().Err(err).Msg("failed to retire signing key")
+
+		switch {
+		case errors.Is(err, usecase.ErrSigningKeyNotFound):
+			return nil, status.Errorf(codes.NotFound, "signing key not found")
+		case errors.Is(err, usecase.ErrRetireActiveSigningKey):
+			return nil, status.Errorf(codes.FailedPrecondition, "active signing key cannot be retired")
+		default:
+			return nil, status.Errorf(codes.Internal, "something went wrong")
+		}
+	}
+
+	return &authpbv1.RetireSigningKeyResponse{}, nil
+}
+
+// newSigningKeyResponse converts a managed signing key into its gRPC representation.
+func newSigningKeyResponse(key *auth.ManagedKey) *authpbv1.SigningKey {
+	pbKey := &authpbv1.SigningKey{
+		Id:        key.Key.ID,
+		Algorithm: key.Key.Algorithm,
+		Status:    key.Status,
+		CreatedAt: timestamppb.New(key.CreatedAt),
+	}
+	if !key.PromotedAt.IsZero() {
+		pbKey.PromotedAt = timestamppb.New(key.PromotedAt)
+	}
+	if !key.ExpiresAt.IsZero() {
+		pbKey.ExpiresAt = timestamppb.New(key.ExpiresAt)
+	}
+
+	return pbKey
+}
+
+// passwordValidationError converts a rejected new password into a validation error for the given field.
+func passwordValidationError(field string, err error) error {
+	message := "has appeared in a data breach, choose a different password"
+
+	var policyErr *security.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		message = strings.Join(policyErr.Violations, ", ")
+	}
+
+	return utilities.NewGRPCValidationError("password is not allowed", []contract.APIValidationError{
+		{
+			Field:   field,
+			Message: field + " " + message,
+		},
+	})
+}
@@ -2,14 +2,55 @@ package domain
 
 import (
 	"context"
+	"time"
 
 	authtypes "github.com/vasapolrittideah/optimize-api/services/auth-service/pkg/types"
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
 )
 
 // AuthUsecase defines the interface for authentication-related use cases.
 type AuthUsecase interface {
-	SignIn(ctx context.Context, params SignInParams) (*authtypes.Tokens, error)
-	SignUp(ctx context.Context, params SignUpParams) (*authtypes.Tokens, error)
+	SignIn(ctx context.Context, params SignInParams) (*SignInResult, error)
+	SignUp(ctx context.Context, params SignUpParams) (*SignUpResult, error)
+	RefreshTokens(ctx context.Context, params RefreshTokensParams) (*authtypes.Tokens, error)
+	SignOut(ctx context.Context, params SignOutParams) error
+	SignOutAll(ctx context.Context, params SignOutAllParams) error
+	ListSessions(ctx context.Context, params ListSessionsParams) ([]*ActiveSession, error)
+	VerifyEmail(ctx context.Context, params VerifyEmailParams) error
+	ResendVerification(ctx context.Context, params ResendVerificationParams) error
+	RequestPasswordReset(ctx context.Context, params RequestPasswordResetParams) error
+	ResetPassword(ctx context.Context, params ResetPasswordParams) error
+	ChangePassword(ctx context.Context, params ChangePasswordParams) error
+	EnrollTOTP(ctx context.Context, params EnrollTOTPParams) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, params ConfirmTOTPParams) error
+	DisableTOTP(ctx context.Context, params DisableTOTPParams) error
+	VerifyMFA(ctx context.Context, params VerifyMFAParams) (*authtypes.Tokens, error)
+	GenerateRecoveryCodes(ctx context.Context, params GenerateRecoveryCodesParams) ([]string, error)
+	RedeemRecoveryCode(ctx context.Context, params RedeemRecoveryCodeParams) (*RecoveryCodeRedemption, error)
+	CountRecoveryCodes(ctx context.Context, params CountRecoveryCodesParams) (int, error)
+	UnlockAccount(ctx context.Context, params UnlockAccountParams) error
+	BeginOAuth(ctx context.Context, params BeginOAuthParams) (*OAuthAuthorization, error)
+	CompleteOAuth(ctx context.Context, params CompleteOAuthParams) (*OAuthCompletion, error)
+	ListIdentities(ctx context.Context, params ListIdentitiesParams) ([]Identity, error)
+	LinkIdentity(ctx context.Context, params LinkIdentityParams) (*OAuthAuthorization, error)
+	UnlinkIdentity(ctx context.Context, params UnlinkIdentityParams) error
+	RequestMagicLink(ctx context.Context, params RequestMagicLinkParams) error
+	ConsumeMagicLink(ctx context.Context, params ConsumeMagicLinkParams) (*SignInResult, error)
+	BeginPasskeyRegistration(ctx context.Context, params BeginPasskeyRegistrationParams) (*PasskeyChallenge, error)
+	FinishPasskeyRegistration(
+		ctx context.Context,
+		params FinishPasskeyRegistrationParams,
+	) (*WebAuthnCredential, error)
+	BeginPasskeyLogin(ctx context.Context, params BeginPasskeyLoginParams) (*PasskeyChallenge, error)
+	FinishPasskeyLogin(ctx context.Context, params FinishPasskeyLoginParams) (*authtypes.Tokens, error)
+	IntrospectToken(ctx context.Context, params IntrospectTokenParams) (*TokenIntrospection, error)
+	RevokeSession(ctx context.Context, params RevokeSessionParams) error
+	ListRevokedSessions(ctx context.Context, params ListRevokedSessionsParams) ([]RevokedSession, error)
+	GetJWKS(ctx context.Context) (*auth.JWKS, error)
+	ListSigningKeys(ctx context.Context) ([]auth.ManagedKey, error)
+	StageSigningKey(ctx context.Context) (*auth.ManagedKey, error)
+	PromoteSigningKey(ctx context.Context, params PromoteSigningKeyParams) error
+	RetireSigningKey(ctx context.Context, params RetireSigningKeyParams) error
 }
 
 // SignInParams defines the parameters for user sign-in.
@@ -18,9 +59,269 @@ type SignInParams struct {
 	Password string
 }
 
+// SignInResult represents the outcome of a successful password check. When the user has
+// multi-factor authentication enabled or has registered a passkey, Tokens is nil and
+// MFAToken must be exchanged for tokens through VerifyMFA or a passkey sign-in.
+type SignInResult struct {
+	Tokens   *authtypes.Tokens
+	MFAToken string
+}
+
 // SignUpParams defines the parameters for user sign-up.
 type SignUpParams struct {
 	Email    string
 	Password string
 	FullName string
 }
+
+// SignUpResult represents the outcome of a sign-up. When verified emails are required, Tokens
+// is nil and the user signs in once the emailed code is confirmed through VerifyEmail.
+type SignUpResult struct {
+	Tokens               *authtypes.Tokens
+	VerificationRequired bool
+}
+
+// RefreshTokensParams defines the parameters for refreshing session tokens.
+type RefreshTokensParams struct {
+	RefreshToken string
+}
+
+// SignOutParams defines the parameters for ending the current session.
+type SignOutParams struct {
+	AccessToken string
+}
+
+// SignOutAllParams defines the parameters for ending every session of a user.
+type SignOutAllParams struct {
+	AccessToken string
+}
+
+// ListSessionsParams defines the parameters for listing the active sessions of a user.
+type ListSessionsParams struct {
+	AccessToken string
+}
+
+// ActiveSession describes an active session of a user along with the device it was created from.
+type ActiveSession struct {
+	ID         string
+	IPAddress  string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	Current    bool
+}
+
+// VerifyEmailParams defines the parameters for verifying the email address of a user.
+type VerifyEmailParams struct {
+	Email string
+	Code  string
+}
+
+// ResendVerificationParams defines the parameters for resending an email verification code.
+type ResendVerificationParams struct {
+	Email string
+}
+
+// RequestPasswordResetParams defines the parameters for requesting a password reset.
+type RequestPasswordResetParams struct {
+	Email string
+}
+
+// ResetPasswordParams defines the parameters for resetting a password with a reset token.
+type ResetPasswordParams struct {
+	Token       string
+	NewPassword string
+}
+
+// ChangePasswordParams defines the parameters for changing the password of a signed-in user.
+type ChangePasswordParams struct {
+	AccessToken         string
+	CurrentPassword     string
+	NewPassword         string
+	RevokeOtherSessions bool
+}
+
+// EnrollTOTPParams defines the parameters for starting TOTP enrollment.
+type EnrollTOTPParams struct {
+	AccessToken string
+}
+
+// TOTPEnrollment contains the secret a user adds to their authenticator app.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// ConfirmTOTPParams defines the parameters for confirming TOTP enrollment.
+type ConfirmTOTPParams struct {
+	AccessToken string
+	Code        string
+}
+
+// DisableTOTPParams defines the parameters for disabling TOTP.
+type DisableTOTPParams struct {
+	AccessToken string
+	Code        string
+}
+
+// VerifyMFAParams defines the parameters for completing a sign-in with a second factor.
+type VerifyMFAParams struct {
+	MFAToken string
+	Code     string
+}
+
+// GenerateRecoveryCodesParams defines the parameters for generating a new set of recovery codes.
+type GenerateRecoveryCodesParams struct {
+	AccessToken string
+}
+
+// RedeemRecoveryCodeParams defines the parameters for signing in with a recovery code.
+type RedeemRecoveryCodeParams struct {
+	Email string
+	Code  string
+}
+
+// RecoveryCodeRedemption represents the outcome of signing in with a recovery code.
+type RecoveryCodeRedemption struct {
+	Tokens                 *authtypes.Tokens
+	PasswordChangeRequired bool
+}
+
+// CountRecoveryCodesParams defines the parameters for counting the remaining recovery codes.
+type CountRecoveryCodesParams struct {
+	AccessToken string
+}
+
+// UnlockAccountParams defines the parameters for lifting a sign-in lockout.
+type UnlockAccountParams struct {
+	UserID string
+}
+
+// BeginOAuthParams defines the parameters for starting a sign-in with an OAuth provider.
+type BeginOAuthParams struct {
+	Provider string
+}
+
+// OAuthAuthorization represents a started OAuth sign-in. The user is sent to URL and the
+// provider redirects back with State, which the caller should bind to the user agent.
+type OAuthAuthorization struct {
+	URL   string
+	State string
+}
+
+// CompleteOAuthParams defines the parameters for completing a sign-in with an OAuth provider.
+type CompleteOAuthParams struct {
+	Provider string
+	State    string
+	Code     string
+}
+
+// OAuthCompletion represents the outcome of a completed OAuth flow. Signing in yields the
+// same result as SignIn, while linking an identity to a signed-in user sets IdentityLinked.
+type OAuthCompletion struct {
+	SignInResult
+
+	IdentityLinked bool
+}
+
+// ListIdentitiesParams defines the parameters for listing the identities of the current user.
+type ListIdentitiesParams struct {
+	AccessToken string
+}
+
+// LinkIdentityParams defines the parameters for linking a provider identity to the current user.
+type LinkIdentityParams struct {
+	AccessToken string
+	Provider    string
+}
+
+// UnlinkIdentityParams defines the parameters for removing an identity from the current user.
+type UnlinkIdentityParams struct {
+	AccessToken string
+	IdentityID  string
+}
+
+// RequestMagicLinkParams defines the parameters for emailing a sign-in link. When CreateUser
+// is set, a user is created for an unknown email address once the link is used.
+type RequestMagicLinkParams struct {
+	Email      string
+	CreateUser bool
+}
+
+// ConsumeMagicLinkParams defines the parameters for signing in with an emailed link.
+type ConsumeMagicLinkParams struct {
+	Token string
+}
+
+// PasskeyChallenge represents the first step of a WebAuthn ceremony. Options holds the JSON
+// encoded options to pass to the WebAuthn API of the browser, and ChallengeID identifies the
+// ceremony when its result is sent back.
+type PasskeyChallenge struct {
+	ChallengeID string
+	Options     []byte
+}
+
+// BeginPasskeyRegistrationParams defines the parameters for starting to register a passkey.
+type BeginPasskeyRegistrationParams struct {
+	AccessToken string
+}
+
+// FinishPasskeyRegistrationParams defines the parameters for registering a passkey with the
+// JSON encoded credential created by the browser.
+type FinishPasskeyRegistrationParams struct {
+	AccessToken string
+	ChallengeID string
+	Credential  []byte
+	Name        string
+}
+
+// BeginPasskeyLoginParams defines the parameters for starting to sign in with a passkey. Without
+// an MFA token, any passkey can be used to sign in without a password. With the MFA token returned
+// by a sign-in, one of the passkeys of that user is requested as the second factor.
+type BeginPasskeyLoginParams struct {
+	MFAToken string
+}
+
+// FinishPasskeyLoginParams defines the parameters for signing in with the JSON encoded
+// assertion made by the browser.
+type FinishPasskeyLoginParams struct {
+	ChallengeID string
+	Credential  []byte
+}
+
+// IntrospectTokenParams defines the parameters for introspecting an access token.
+type IntrospectTokenParams struct {
+	Token string
+}
+
+// TokenIntrospection represents the state of an access token, following RFC 7662. Only Active
+// is set, to false, for tokens that are invalid, expired or issued for a session that has ended.
+type TokenIntrospection struct {
+	Active    bool
+	UserID    string
+	SessionID string
+	Scopes    []string
+	ExpiresAt time.Time
+	IssuedAt  time.Time
+}
+
+// RevokeSessionParams defines the parameters for revoking a session and its access tokens.
+type RevokeSessionParams struct {
+	SessionID string
+}
+
+// ListRevokedSessionsParams defines the parameters for listing the sessions revoked since a
+// point in time. A zero Since lists every revoked session whose access tokens have not expired.
+type ListRevokedSessionsParams struct {
+	Since time.Time
+}
+
+// PromoteSigningKeyParams defines the parameters for making a staged key the active signing key.
+type PromoteSigningKeyParams struct {
+	KeyID string
+}
+
+// RetireSigningKeyParams defines the parameters for retiring a signing key.
+type RetireSigningKeyParams struct {
+	KeyID string
+}
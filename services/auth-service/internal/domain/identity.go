@@ -7,6 +7,13 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+const (
+	// IdentityProviderEmail identifies the identity of users who sign in with their email and password.
+	IdentityProviderEmail = "email"
+	// IdentityProviderMagicLink identifies the identity of users who sign in with links sent to their email.
+	IdentityProviderMagicLink = "magic_link"
+)
+
 // Identity represents a user's identity in the authentication system.
 // It stores the mapping between a user and their identities from both external
 // providers (Google, Facebook, etc.) and local authentication (email and password).
@@ -26,5 +33,6 @@ type IdentityRepository interface {
 	CreateIdentity(ctx context.Context, identity *Identity) (*Identity, error)
 	GetIdentitiesByUserID(ctx context.Context, userID string) ([]Identity, error)
 	GetIdentityByProvider(ctx context.Context, providerID string, provider string) (*Identity, error)
-	UpdateLastLogin(ctx context.Context, userID string) error
+	UpdateLastLogin(ctx context.Context, id string) error
+	DeleteIdentity(ctx context.Context, id, userID string) error
 }
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// LoginAttempt tracks consecutive failed sign-ins for an account. Attempts with an
+// empty IPAddress count failures across every client, while the others count
+// failures from a single client IP address.
+type LoginAttempt struct {
+	ID           bson.ObjectID `bson:"_id,omitempty"`
+	UserID       string        `bson:"user_id"`
+	IPAddress    string        `bson:"ip_address"`
+	Failures     int           `bson:"failures"`
+	LockedUntil  *time.Time    `bson:"locked_until"`
+	LastFailedAt time.Time     `bson:"last_failed_at"`
+	ExpiresAt    time.Time     `bson:"expires_at"`
+}
+
+// LoginAttemptRepository defines the interface for failed sign-in tracking database operations.
+type LoginAttemptRepository interface {
+	GetLoginAttempt(ctx context.Context, userID, ipAddress string) (*LoginAttempt, error)
+	RecordFailedAttempt(ctx context.Context, userID, ipAddress string, expiresAt time.Time) (*LoginAttempt, error)
+	LockLoginAttempt(ctx context.Context, id string, lockedUntil time.Time) error
+	DeleteLoginAttemptsByUserID(ctx context.Context, userID string) error
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// OAuthState represents a pending OAuth flow. It binds the state sent to the provider
+// to the nonce and PKCE code verifier needed to complete the flow. UserID is only set
+// when the flow links an identity to a signed-in user. Only the hash of the state is stored.
+type OAuthState struct {
+	ID           bson.ObjectID `bson:"_id,omitempty"`
+	StateHash    string        `bson:"state_hash"`
+	Provider     string        `bson:"provider"`
+	UserID       string        `bson:"user_id,omitempty"`
+	Nonce        string        `bson:"nonce"`
+	CodeVerifier string        `bson:"code_verifier"`
+	ExpiresAt    time.Time     `bson:"expires_at"`
+	CreatedAt    time.Time     `bson:"created_at"`
+}
+
+// OAuthStateRepository defines the interface for OAuth state database operations.
+type OAuthStateRepository interface {
+	CreateState(ctx context.Context, state *OAuthState) (*OAuthState, error)
+	ConsumeState(ctx context.Context, provider, stateHash string) (*OAuthState, error)
+}
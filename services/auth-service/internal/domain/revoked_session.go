@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// RevokedSession represents a revoked session whose access tokens have not all expired yet.
+// It is kept until ExpiresAt, when the last access token issued for the session expires, so
+// that the services verifying access tokens can reject them before then.
+type RevokedSession struct {
+	ID        bson.ObjectID `bson:"_id,omitempty"`
+	SessionID string        `bson:"session_id"`
+	ExpiresAt time.Time     `bson:"expires_at"`
+	RevokedAt time.Time     `bson:"revoked_at"`
+}
+
+// RevokedSessionRepository defines the interface for revoked session database operations.
+type RevokedSessionRepository interface {
+	CreateRevokedSessions(ctx context.Context, revokedSessions []*RevokedSession) error
+	ListRevokedSessions(ctx context.Context, since time.Time) ([]RevokedSession, error)
+}
@@ -8,15 +8,27 @@ import (
 )
 
 // Session represents an authentication user session with access and refresh tokens.
+// Every refresh token exchange rotates the session into a new child session that
+// shares the same family, so a replayed refresh token can be traced back to its chain.
+// The family ID is the ID of the first session of the family. Sessions created before
+// families were introduced have none and are a family of their own.
+// Only the hash of the refresh token is stored. RefreshToken holds the plaintext token of
+// sessions issued before hashes were stored, and is removed when their tokens are replaced.
 type Session struct {
 	ID                    bson.ObjectID `bson:"_id,omitempty"`
 	UserID                string        `bson:"user_id"`
-	AccessToken           string        `bson:"access_token"`
-	RefreshToken          string        `bson:"refresh_token"`
+	FamilyID              string        `bson:"family_id"`
+	ParentID              *string       `bson:"parent_id"`
+	Generation            int           `bson:"generation"`
+	RefreshTokenHash      string        `bson:"refresh_token_hash"`
+	RefreshToken          string        `bson:"refresh_token,omitempty"`
 	AccessTokenExpiresAt  time.Time     `bson:"access_token_expires_at"`
 	RefreshTokenExpiresAt time.Time     `bson:"refresh_token_expires_at"`
 	IPAddress             *string       `bson:"ip_address"`
 	UserAgent             *string       `bson:"user_agent"`
+	AuthenticatedAt       time.Time     `bson:"authenticated_at"`
+	RotatedAt             *time.Time    `bson:"rotated_at"`
+	RevokedAt             *time.Time    `bson:"revoked_at"`
 	CreatedAt             time.Time     `bson:"created_at"`
 	UpdatedAt             time.Time     `bson:"updated_at"`
 }
@@ -24,14 +36,19 @@ type Session struct {
 // SessionRepository defines the interface for session-related database operations.
 type SessionRepository interface {
 	CreateSession(ctx context.Context, session *Session) (*Session, error)
+	GetSession(ctx context.Context, id string) (*Session, error)
 	GetSessionByUserID(ctx context.Context, userID string) (*Session, error)
+	ListActiveSessionsByUserID(ctx context.Context, userID string) ([]*Session, error)
 	UpdateTokens(ctx context.Context, id string, params UpdateTokensParams) (*Session, error)
+	RotateSession(ctx context.Context, id string) (*Session, error)
+	RevokeSessionFamily(ctx context.Context, familyID string) ([]*Session, error)
+	RevokeSessionsByUserID(ctx context.Context, userID string) ([]*Session, error)
+	RevokeOtherSessions(ctx context.Context, userID, keepFamilyID string) ([]*Session, error)
 }
 
 // UpdateTokensParams defines the parameters for updating session tokens.
 type UpdateTokensParams struct {
-	AccessToken           string    `bson:"access_token"`
-	RefreshToken          string    `bson:"refresh_token"`
+	RefreshTokenHash      string    `bson:"refresh_token_hash"`
 	AccessTokenExpiresAt  time.Time `bson:"access_token_expires_at"`
 	RefreshTokenExpiresAt time.Time `bson:"refresh_token_expires_at"`
 }
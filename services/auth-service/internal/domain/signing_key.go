@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// SigningKey represents a key that access tokens are signed with, shared by every instance of
+// the auth service so that they rotate keys together. The private key is stored encrypted.
+type SigningKey struct {
+	ID         bson.ObjectID `bson:"_id,omitempty"`
+	KeyID      string        `bson:"key_id"`
+	Algorithm  string        `bson:"algorithm"`
+	Status     string        `bson:"status"`
+	PrivateKey string        `bson:"private_key"`
+	PromotedAt *time.Time    `bson:"promoted_at"`
+	ExpiresAt  *time.Time    `bson:"expires_at"`
+	CreatedAt  time.Time     `bson:"created_at"`
+	UpdatedAt  time.Time     `bson:"updated_at"`
+}
+
+// SigningKeyRepository defines the interface for signing key database operations.
+type SigningKeyRepository interface {
+	GetSigningKeys(ctx context.Context) ([]SigningKey, error)
+	UpsertSigningKey(ctx context.Context, key *SigningKey) error
+	DeleteSigningKey(ctx context.Context, keyID string) error
+}
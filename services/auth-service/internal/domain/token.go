@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	// TokenPurposePasswordReset identifies tokens that allow a user to reset their password.
+	TokenPurposePasswordReset = "password_reset"
+	// TokenPurposeMagicLink identifies tokens that sign a user in without a password.
+	TokenPurposeMagicLink = "magic_link"
+	// TokenPurposeMFAChallenge identifies the challenges behind MFA tokens, which count the
+	// codes tried with the token.
+	TokenPurposeMFAChallenge = "mfa_challenge"
+)
+
+// OneTimeToken represents a single-use token sent to a user out of band.
+// Only the hash of the token is stored.
+type OneTimeToken struct {
+	ID         bson.ObjectID `bson:"_id,omitempty"`
+	UserID     string        `bson:"user_id"`
+	Purpose    string        `bson:"purpose"`
+	TokenHash  string        `bson:"token_hash"`
+	ExpiresAt  time.Time     `bson:"expires_at"`
+	Attempts   int           `bson:"attempts"`
+	ConsumedAt *time.Time    `bson:"consumed_at"`
+	CreatedAt  time.Time     `bson:"created_at"`
+}
+
+// OneTimeTokenRepository defines the interface for one-time token database operations.
+type OneTimeTokenRepository interface {
+	CreateToken(ctx context.Context, token *OneTimeToken) (*OneTimeToken, error)
+	GetToken(ctx context.Context, purpose, tokenHash string) (*OneTimeToken, error)
+	ConsumeToken(ctx context.Context, purpose, tokenHash string) (*OneTimeToken, error)
+	IncrementTokenAttempts(ctx context.Context, purpose, tokenHash string) (*OneTimeToken, error)
+	DeleteTokensByUserID(ctx context.Context, userID, purpose string) error
+}
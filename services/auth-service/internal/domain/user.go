@@ -7,6 +7,13 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// Roles that can be granted to users. Users without any role, including users created before
+// roles were introduced, have RoleUser.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 // User represents a user in the authentication system.
 type User struct {
 	ID                        bson.ObjectID `bson:"_id,omitempty"`
@@ -16,6 +23,15 @@ type User struct {
 	Verified                  bool          `bson:"verified"`
 	VerificationCode          string        `bson:"verification_code"`
 	VerificationCodeExpiresAt time.Time     `bson:"verification_code_expires_at"`
+	VerificationCodeSentAt    time.Time     `bson:"verification_code_sent_at"`
+	VerificationAttempts      int           `bson:"verification_attempts"`
+	TOTPSecret                string        `bson:"totp_secret"`
+	TOTPEnabled               bool          `bson:"totp_enabled"`
+	TOTPLastUsedStep          int64         `bson:"totp_last_used_step"`
+	RecoveryCodes             []string      `bson:"recovery_codes"`
+	PasswordChangeRequired    bool          `bson:"password_change_required"`
+	RecoveryFamilyID          string        `bson:"recovery_family_id"`
+	Roles                     []string      `bson:"roles"`
 	CreatedAt                 time.Time     `bson:"created_at"`
 	UpdatedAt                 time.Time     `bson:"updated_at"`
 }
@@ -28,14 +44,29 @@ type UserRepository interface {
 	UpdateUser(ctx context.Context, id string, params UpdateUserParams) (*User, error)
 	DeleteUser(ctx context.Context, id string) (*User, error)
 	ListUsers(ctx context.Context, params FilterUsersParams) ([]*User, error)
+	IncrementVerificationAttempts(ctx context.Context, id string) (*User, error)
+	UseTOTPStep(ctx context.Context, id string, step int64) error
+	RemoveRecoveryCode(ctx context.Context, id string, codeHash string) error
 }
 
 // UpdateUserParams defines the optional parameters for updating a user.
 // Only the fields that are not nil will be updated.
 type UpdateUserParams struct {
-	Email        *string
-	FullName     *string
-	PasswordHash *string
+	Email                     *string
+	FullName                  *string
+	PasswordHash              *string
+	Verified                  *bool
+	VerificationCode          *string
+	VerificationCodeExpiresAt *time.Time
+	VerificationCodeSentAt    *time.Time
+	VerificationAttempts      *int
+	TOTPSecret                *string
+	TOTPEnabled               *bool
+	TOTPLastUsedStep          *int64
+	RecoveryCodes             *[]string
+	PasswordChangeRequired    *bool
+	RecoveryFamilyID          *string
+	Roles                     *[]string
 }
 
 // FilterUsersParams defines the parameters for filtering and paginating users.
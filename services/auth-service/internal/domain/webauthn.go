@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	// WebAuthnCeremonyRegistration identifies challenges that register a new passkey.
+	WebAuthnCeremonyRegistration = "registration"
+	// WebAuthnCeremonyLogin identifies challenges that sign a user in with a passkey.
+	WebAuthnCeremonyLogin = "login"
+)
+
+// WebAuthnCredential represents a passkey registered by a user. It stores the public key
+// and the authenticator state needed to verify later assertions made with the passkey.
+type WebAuthnCredential struct {
+	ID              bson.ObjectID `bson:"_id,omitempty"`
+	UserID          string        `bson:"user_id"`
+	Name            string        `bson:"name"`
+	CredentialID    []byte        `bson:"credential_id"`
+	PublicKey       []byte        `bson:"public_key"`
+	AttestationType string        `bson:"attestation_type"`
+	Transports      []string      `bson:"transports"`
+	AAGUID          []byte        `bson:"aaguid"`
+	SignCount       uint32        `bson:"sign_count"`
+	CloneWarning    bool          `bson:"clone_warning"`
+	BackupEligible  bool          `bson:"backup_eligible"`
+	BackupState     bool          `bson:"backup_state"`
+	LastUsedAt      *time.Time    `bson:"last_used_at"`
+	CreatedAt       time.Time     `bson:"created_at"`
+	UpdatedAt       time.Time     `bson:"updated_at"`
+}
+
+// WebAuthnCredentialRepository defines the interface for passkey database operations.
+type WebAuthnCredentialRepository interface {
+	CreateCredential(ctx context.Context, credential *WebAuthnCredential) (*WebAuthnCredential, error)
+	GetCredentialsByUserID(ctx context.Context, userID string) ([]WebAuthnCredential, error)
+	UpdateCredential(ctx context.Context, id string, params UpdateWebAuthnCredentialParams) error
+}
+
+// UpdateWebAuthnCredentialParams defines the optional parameters for updating a passkey.
+// Only the fields that are not nil will be updated.
+type UpdateWebAuthnCredentialParams struct {
+	SignCount    *uint32
+	CloneWarning *bool
+	BackupState  *bool
+	LastUsedAt   *time.Time
+}
+
+// WebAuthnChallenge represents a pending WebAuthn ceremony. It holds the session data of the
+// WebAuthn library between the begin and finish steps of the ceremony. UserID is empty for
+// passwordless sign-ins, where the user is only known once the passkey is presented.
+// IdentityID is the identity the user signed in with when the passkey is their second factor,
+// and MFATokenHash the hash of the ID of the MFA token that is consumed once it is presented.
+// Only the hash of the challenge ID is stored.
+type WebAuthnChallenge struct {
+	ID           bson.ObjectID `bson:"_id,omitempty"`
+	TokenHash    string        `bson:"token_hash"`
+	Ceremony     string        `bson:"ceremony"`
+	UserID       string        `bson:"user_id,omitempty"`
+	IdentityID   string        `bson:"identity_id,omitempty"`
+	MFATokenHash string        `bson:"mfa_token_hash,omitempty"`
+	SessionData  []byte        `bson:"session_data"`
+	ExpiresAt    time.Time     `bson:"expires_at"`
+	CreatedAt    time.Time     `bson:"created_at"`
+}
+
+// WebAuthnChallengeRepository defines the interface for WebAuthn challenge database operations.
+type WebAuthnChallengeRepository interface {
+	CreateChallenge(ctx context.Context, challenge *WebAuthnChallenge) (*WebAuthnChallenge, error)
+	ConsumeChallenge(ctx context.Context, ceremony, tokenHash string) (*WebAuthnChallenge, error)
+}
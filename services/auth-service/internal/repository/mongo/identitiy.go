@@ -5,8 +5,10 @@ import (
 	"errors"
 	"time"
 
+	"github.com/rs/zerolog"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 
 	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
 )
@@ -17,7 +19,31 @@ type identityMongoRepository struct {
 	db *mongo.Database
 }
 
-func NewIdentityMongoRepository(db *mongo.Database) domain.IdentityRepository {
+func NewIdentityMongoRepository(
+	ctx context.Context,
+	logger *zerolog.Logger,
+	db *mongo.Database,
+) domain.IdentityRepository {
+	collection := db.Collection(identityCollection)
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			// Email identities have no provider ID, so only external identities are unique.
+			Keys: bson.D{{Key: "provider", Value: 1}, {Key: "provider_id", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"provider_id": bson.M{"$gt": ""}}),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create identity indexes")
+	}
+
 	return &identityMongoRepository{db: db}
 }
 
@@ -78,11 +104,46 @@ func (r *identityMongoRepository) GetIdentityByProvider(
 	return &identity, nil
 }
 
-func (r *identityMongoRepository) UpdateLastLogin(ctx context.Context, userID string) error {
-	_, err := r.db.Collection(identityCollection).UpdateOne(
+func (r *identityMongoRepository) UpdateLastLogin(ctx context.Context, id string) error {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result, err := r.db.Collection(identityCollection).UpdateOne(
 		ctx,
-		bson.M{"user_id": userID},
-		bson.M{"$set": bson.M{"last_login_at": time.Now()}},
+		bson.M{"_id": objectID},
+		bson.M{"$set": bson.M{"last_login_at": now, "updated_at": now}},
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *identityMongoRepository) DeleteIdentity(ctx context.Context, id, userID string) error {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Collection(identityCollection).DeleteOne(ctx, bson.M{
+		"_id":     objectID,
+		"user_id": userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
 }
@@ -0,0 +1,136 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+)
+
+const loginAttemptCollection = "login_attempts"
+
+type loginAttemptMongoRepository struct {
+	db *mongo.Database
+}
+
+func NewLoginAttemptMongoRepository(
+	ctx context.Context,
+	logger *zerolog.Logger,
+	db *mongo.Database,
+) domain.LoginAttemptRepository {
+	collection := db.Collection(loginAttemptCollection)
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "ip_address", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create login attempt indexes")
+	}
+
+	return &loginAttemptMongoRepository{db: db}
+}
+
+func (r *loginAttemptMongoRepository) GetLoginAttempt(
+	ctx context.Context,
+	userID string,
+	ipAddress string,
+) (*domain.LoginAttempt, error) {
+	var attempt domain.LoginAttempt
+	if err := r.db.Collection(loginAttemptCollection).FindOne(ctx, bson.M{
+		"user_id":    userID,
+		"ip_address": ipAddress,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&attempt); err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+func (r *loginAttemptMongoRepository) RecordFailedAttempt(
+	ctx context.Context,
+	userID string,
+	ipAddress string,
+	expiresAt time.Time,
+) (*domain.LoginAttempt, error) {
+	now := time.Now()
+
+	// Failures are counted with a single upsert so that concurrent attempts
+	// cannot overwrite each other's increments.
+	recordFailure := func() *mongo.SingleResult {
+		return r.db.Collection(loginAttemptCollection).FindOneAndUpdate(
+			ctx,
+			bson.M{"user_id": userID, "ip_address": ipAddress},
+			bson.M{
+				"$inc": bson.M{"failures": 1},
+				"$set": bson.M{
+					"last_failed_at": now,
+					"expires_at":     expiresAt,
+				},
+			},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		)
+	}
+
+	result := recordFailure()
+	// Two concurrent first failures may both try to insert the attempt; the one that
+	// loses on the unique index retries, which now updates the inserted attempt.
+	if mongo.IsDuplicateKeyError(result.Err()) {
+		result = recordFailure()
+	}
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var attempt domain.LoginAttempt
+	if err := result.Decode(&attempt); err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+func (r *loginAttemptMongoRepository) LockLoginAttempt(ctx context.Context, id string, lockedUntil time.Time) error {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Collection(loginAttemptCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": objectID},
+		bson.M{
+			"$set": bson.M{"locked_until": lockedUntil},
+			// The attempt must outlive its lock, otherwise the TTL index would lift it early.
+			"$max": bson.M{"expires_at": lockedUntil},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *loginAttemptMongoRepository) DeleteLoginAttemptsByUserID(ctx context.Context, userID string) error {
+	_, err := r.db.Collection(loginAttemptCollection).DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
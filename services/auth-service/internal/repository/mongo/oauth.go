@@ -0,0 +1,89 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+)
+
+const oauthStateCollection = "oauth_states"
+
+type oauthStateMongoRepository struct {
+	db *mongo.Database
+}
+
+func NewOAuthStateMongoRepository(
+	ctx context.Context,
+	logger *zerolog.Logger,
+	db *mongo.Database,
+) domain.OAuthStateRepository {
+	collection := db.Collection(oauthStateCollection)
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "state_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create OAuth state indexes")
+	}
+
+	return &oauthStateMongoRepository{db: db}
+}
+
+func (r *oauthStateMongoRepository) CreateState(
+	ctx context.Context,
+	state *domain.OAuthState,
+) (*domain.OAuthState, error) {
+	state.CreatedAt = time.Now()
+
+	result, err := r.db.Collection(oauthStateCollection).InsertOne(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	if objectID, ok := result.InsertedID.(bson.ObjectID); ok {
+		state.ID = objectID
+	} else {
+		return nil, errors.New("failed to convert inserted ID to ObjectID")
+	}
+
+	return state, nil
+}
+
+func (r *oauthStateMongoRepository) ConsumeState(
+	ctx context.Context,
+	provider string,
+	stateHash string,
+) (*domain.OAuthState, error) {
+	// The state is deleted as it is read so that a callback can never be replayed.
+	result := r.db.Collection(oauthStateCollection).FindOneAndDelete(ctx, bson.M{
+		"provider":   provider,
+		"state_hash": stateHash,
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var state domain.OAuthState
+	if err := result.Decode(&state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,92 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+)
+
+const revokedSessionCollection = "revoked_sessions"
+
+type revokedSessionMongoRepository struct {
+	db *mongo.Database
+}
+
+func NewRevokedSessionMongoRepository(
+	ctx context.Context,
+	logger *zerolog.Logger,
+	db *mongo.Database,
+) domain.RevokedSessionRepository {
+	collection := db.Collection(revokedSessionCollection)
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "session_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "revoked_at", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create revoked session indexes")
+	}
+
+	return &revokedSessionMongoRepository{db: db}
+}
+
+func (r *revokedSessionMongoRepository) CreateRevokedSessions(
+	ctx context.Context,
+	revokedSessions []*domain.RevokedSession,
+) error {
+	if len(revokedSessions) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(revokedSessions))
+	for _, revokedSession := range revokedSessions {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"session_id": revokedSession.SessionID}).
+			SetUpdate(bson.M{"$set": bson.M{
+				"expires_at": revokedSession.ExpiresAt,
+				"revoked_at": revokedSession.RevokedAt,
+			}}).
+			SetUpsert(true))
+	}
+
+	_, err := r.db.Collection(revokedSessionCollection).BulkWrite(ctx, models)
+
+	return err
+}
+
+func (r *revokedSessionMongoRepository) ListRevokedSessions(
+	ctx context.Context,
+	since time.Time,
+) ([]domain.RevokedSession, error) {
+	cursor, err := r.db.Collection(revokedSessionCollection).Find(ctx, bson.M{
+		"revoked_at": bson.M{"$gte": since},
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var revokedSessions []domain.RevokedSession
+	if err := cursor.All(ctx, &revokedSessions); err != nil {
+		return nil, err
+	}
+
+	return revokedSessions, nil
+}
@@ -5,19 +5,39 @@ import (
 	"errors"
 	"time"
 
+	"github.com/rs/zerolog"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 
 	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
 )
 
 const sessionCollection = "sessions"
 
+var errEmptyFamilyID = errors.New("session family ID is empty")
+
 type sessionMongoRepository struct {
 	db *mongo.Database
 }
 
-func NewSessionMongoRepository(db *mongo.Database) domain.SessionRepository {
+func NewSessionMongoRepository(
+	ctx context.Context,
+	logger *zerolog.Logger,
+	db *mongo.Database,
+) domain.SessionRepository {
+	collection := db.Collection(sessionCollection)
+
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create session indexes")
+	}
+
 	return &sessionMongoRepository{db: db}
 }
 
@@ -40,6 +60,25 @@ func (r *sessionMongoRepository) CreateSession(ctx context.Context, session *dom
 	return session, nil
 }
 
+func (r *sessionMongoRepository) GetSession(ctx context.Context, id string) (*domain.Session, error) {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := r.db.Collection(sessionCollection).FindOne(ctx, bson.M{"_id": objectID})
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var session domain.Session
+	if err := result.Decode(&session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
 func (r *sessionMongoRepository) GetSessionByUserID(ctx context.Context, userID string) (*domain.Session, error) {
 	result := r.db.Collection(sessionCollection).FindOne(ctx, bson.M{"user_id": userID})
 	if result.Err() != nil {
@@ -54,6 +93,31 @@ func (r *sessionMongoRepository) GetSessionByUserID(ctx context.Context, userID
 	return &session, nil
 }
 
+func (r *sessionMongoRepository) ListActiveSessionsByUserID(
+	ctx context.Context,
+	userID string,
+) ([]*domain.Session, error) {
+	filter := bson.M{
+		"user_id":                  userID,
+		"rotated_at":               nil,
+		"revoked_at":               nil,
+		"refresh_token_expires_at": bson.M{"$gt": time.Now()},
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.db.Collection(sessionCollection).Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*domain.Session
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
 func (r *sessionMongoRepository) UpdateTokens(
 	ctx context.Context,
 	id string,
@@ -64,10 +128,38 @@ func (r *sessionMongoRepository) UpdateTokens(
 		return nil, err
 	}
 
+	// The plaintext tokens stored by earlier versions are removed along the way.
 	result := r.db.Collection(sessionCollection).FindOneAndUpdate(
 		ctx,
 		bson.M{"_id": objectID},
-		bson.M{"$set": params},
+		bson.M{"$set": params, "$unset": bson.M{"access_token": "", "refresh_token": ""}},
+	)
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var session domain.Session
+	if err := result.Decode(&session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (r *sessionMongoRepository) RotateSession(ctx context.Context, id string) (*domain.Session, error) {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only a live session can be rotated, so concurrent exchanges of the same
+	// refresh token cannot both succeed.
+	now := time.Now()
+	result := r.db.Collection(sessionCollection).FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": objectID, "rotated_at": nil, "revoked_at": nil},
+		bson.M{"$set": bson.M{"rotated_at": now, "updated_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
 	)
 	if result.Err() != nil {
 		return nil, result.Err()
@@ -80,3 +172,84 @@ func (r *sessionMongoRepository) UpdateTokens(
 
 	return &session, nil
 }
+
+// RevokeSessionFamily revokes the sessions of the family. The family ID is the ID of its first
+// session, which is matched by ID as well, since sessions created before families were
+// introduced have no family ID stored.
+func (r *sessionMongoRepository) RevokeSessionFamily(
+	ctx context.Context,
+	familyID string,
+) ([]*domain.Session, error) {
+	firstSessionID, err := familyFirstSessionID(familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.revokeSessions(ctx, bson.M{"$or": bson.A{
+		bson.M{"family_id": familyID},
+		bson.M{"_id": firstSessionID},
+	}})
+}
+
+func (r *sessionMongoRepository) RevokeSessionsByUserID(
+	ctx context.Context,
+	userID string,
+) ([]*domain.Session, error) {
+	return r.revokeSessions(ctx, bson.M{"user_id": userID})
+}
+
+func (r *sessionMongoRepository) RevokeOtherSessions(
+	ctx context.Context,
+	userID, keepFamilyID string,
+) ([]*domain.Session, error) {
+	firstSessionID, err := familyFirstSessionID(keepFamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.revokeSessions(ctx, bson.M{
+		"user_id":   userID,
+		"family_id": bson.M{"$ne": keepFamilyID},
+		"_id":       bson.M{"$ne": firstSessionID},
+	})
+}
+
+// familyFirstSessionID returns the ID of the first session of the family. An empty family ID
+// is rejected, since it would match every session created before families were introduced.
+func familyFirstSessionID(familyID string) (bson.ObjectID, error) {
+	if familyID == "" {
+		return bson.ObjectID{}, errEmptyFamilyID
+	}
+
+	return bson.ObjectIDFromHex(familyID)
+}
+
+// revokeSessions revokes the sessions matching the filter that are not revoked yet and returns
+// them. The sessions are found again by their revocation time, which is truncated to the
+// millisecond precision of MongoDB dates so that it matches the stored value.
+func (r *sessionMongoRepository) revokeSessions(ctx context.Context, filter bson.M) ([]*domain.Session, error) {
+	now := time.Now().Truncate(time.Millisecond)
+	collection := r.db.Collection(sessionCollection)
+
+	filter["revoked_at"] = nil
+	if _, err := collection.UpdateMany(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}},
+	); err != nil {
+		return nil, err
+	}
+
+	filter["revoked_at"] = now
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*domain.Session
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
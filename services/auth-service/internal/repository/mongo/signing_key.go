@@ -0,0 +1,93 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+)
+
+const signingKeyCollection = "signing_keys"
+
+type signingKeyMongoRepository struct {
+	db *mongo.Database
+}
+
+func NewSigningKeyMongoRepository(
+	ctx context.Context,
+	logger *zerolog.Logger,
+	db *mongo.Database,
+) domain.SigningKeyRepository {
+	collection := db.Collection(signingKeyCollection)
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create signing key indexes")
+	}
+
+	return &signingKeyMongoRepository{db: db}
+}
+
+func (r *signingKeyMongoRepository) GetSigningKeys(ctx context.Context) ([]domain.SigningKey, error) {
+	cursor, err := r.db.Collection(signingKeyCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []domain.SigningKey
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *signingKeyMongoRepository) UpsertSigningKey(ctx context.Context, key *domain.SigningKey) error {
+	now := time.Now()
+
+	_, err := r.db.Collection(signingKeyCollection).UpdateOne(
+		ctx,
+		bson.M{"key_id": key.KeyID},
+		bson.M{
+			"$set": bson.M{
+				"algorithm":   key.Algorithm,
+				"status":      key.Status,
+				"private_key": key.PrivateKey,
+				"promoted_at": key.PromotedAt,
+				"expires_at":  key.ExpiresAt,
+				"updated_at":  now,
+			},
+			"$setOnInsert": bson.M{
+				"created_at": key.CreatedAt,
+			},
+		},
+		options.UpdateOne().SetUpsert(true),
+	)
+
+	return err
+}
+
+func (r *signingKeyMongoRepository) DeleteSigningKey(ctx context.Context, keyID string) error {
+	result, err := r.db.Collection(signingKeyCollection).DeleteOne(ctx, bson.M{"key_id": keyID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+)
+
+const oneTimeTokenCollection = "one_time_tokens"
+
+type oneTimeTokenMongoRepository struct {
+	db *mongo.Database
+}
+
+func NewOneTimeTokenMongoRepository(
+	ctx context.Context,
+	logger *zerolog.Logger,
+	db *mongo.Database,
+) domain.OneTimeTokenRepository {
+	collection := db.Collection(oneTimeTokenCollection)
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create one-time token indexes")
+	}
+
+	return &oneTimeTokenMongoRepository{db: db}
+}
+
+func (r *oneTimeTokenMongoRepository) CreateToken(
+	ctx context.Context,
+	token *domain.OneTimeToken,
+) (*domain.OneTimeToken, error) {
+	token.CreatedAt = time.Now()
+
+	result, err := r.db.Collection(oneTimeTokenCollection).InsertOne(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if objectID, ok := result.InsertedID.(bson.ObjectID); ok {
+		token.ID = objectID
+	} else {
+		return nil, errors.New("failed to convert inserted ID to ObjectID")
+	}
+
+	return token, nil
+}
+
+func (r *oneTimeTokenMongoRepository) GetToken(
+	ctx context.Context,
+	purpose string,
+	tokenHash string,
+) (*domain.OneTimeToken, error) {
+	result := r.db.Collection(oneTimeTokenCollection).FindOne(ctx, bson.M{
+		"purpose":     purpose,
+		"token_hash":  tokenHash,
+		"consumed_at": nil,
+		"expires_at":  bson.M{"$gt": time.Now()},
+	})
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var token domain.OneTimeToken
+	if err := result.Decode(&token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *oneTimeTokenMongoRepository) ConsumeToken(
+	ctx context.Context,
+	purpose string,
+	tokenHash string,
+) (*domain.OneTimeToken, error) {
+	now := time.Now()
+
+	// The token is matched and marked as consumed in a single operation so that
+	// it can never be redeemed twice.
+	result := r.db.Collection(oneTimeTokenCollection).FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"purpose":     purpose,
+			"token_hash":  tokenHash,
+			"consumed_at": nil,
+			"expires_at":  bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"consumed_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var token domain.OneTimeToken
+	if err := result.Decode(&token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *oneTimeTokenMongoRepository) IncrementTokenAttempts(
+	ctx context.Context,
+	purpose string,
+	tokenHash string,
+) (*domain.OneTimeToken, error) {
+	result := r.db.Collection(oneTimeTokenCollection).FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"purpose":     purpose,
+			"token_hash":  tokenHash,
+			"consumed_at": nil,
+			"expires_at":  bson.M{"$gt": time.Now()},
+		},
+		bson.M{"$inc": bson.M{"attempts": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var token domain.OneTimeToken
+	if err := result.Decode(&token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *oneTimeTokenMongoRepository) DeleteTokensByUserID(ctx context.Context, userID, purpose string) error {
+	_, err := r.db.Collection(oneTimeTokenCollection).DeleteMany(ctx, bson.M{
+		"user_id": userID,
+		"purpose": purpose,
+	})
+	return err
+}
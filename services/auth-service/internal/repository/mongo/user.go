@@ -110,6 +110,42 @@ func (r *userMongoRepository) UpdateUser(
 	if params.PasswordHash != nil {
 		updateMap["password_hash"] = params.PasswordHash
 	}
+	if params.Verified != nil {
+		updateMap["verified"] = params.Verified
+	}
+	if params.VerificationCode != nil {
+		updateMap["verification_code"] = params.VerificationCode
+	}
+	if params.VerificationCodeExpiresAt != nil {
+		updateMap["verification_code_expires_at"] = params.VerificationCodeExpiresAt
+	}
+	if params.VerificationCodeSentAt != nil {
+		updateMap["verification_code_sent_at"] = params.VerificationCodeSentAt
+	}
+	if params.VerificationAttempts != nil {
+		updateMap["verification_attempts"] = params.VerificationAttempts
+	}
+	if params.TOTPSecret != nil {
+		updateMap["totp_secret"] = params.TOTPSecret
+	}
+	if params.TOTPEnabled != nil {
+		updateMap["totp_enabled"] = params.TOTPEnabled
+	}
+	if params.TOTPLastUsedStep != nil {
+		updateMap["totp_last_used_step"] = params.TOTPLastUsedStep
+	}
+	if params.RecoveryCodes != nil {
+		updateMap["recovery_codes"] = params.RecoveryCodes
+	}
+	if params.PasswordChangeRequired != nil {
+		updateMap["password_change_required"] = params.PasswordChangeRequired
+	}
+	if params.RecoveryFamilyID != nil {
+		updateMap["recovery_family_id"] = params.RecoveryFamilyID
+	}
+	if params.Roles != nil {
+		updateMap["roles"] = params.Roles
+	}
 
 	if len(updateMap) == 0 {
 		return nil, errors.New("no user fields to update")
@@ -208,3 +244,86 @@ func (r *userMongoRepository) ListUsers(ctx context.Context, params domain.Filte
 
 	return users, nil
 }
+
+func (r *userMongoRepository) IncrementVerificationAttempts(ctx context.Context, id string) (*domain.User, error) {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := r.db.Collection(userCollection).FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": objectID},
+		bson.M{
+			"$inc": bson.M{"verification_attempts": 1},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var user domain.User
+	if err := result.Decode(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *userMongoRepository) UseTOTPStep(ctx context.Context, id string, step int64) error {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	// The step only moves forward, so a code can never be accepted twice. Users who have
+	// never used a code have no step recorded yet.
+	result, err := r.db.Collection(userCollection).UpdateOne(
+		ctx,
+		bson.M{
+			"_id": objectID,
+			"$or": bson.A{
+				bson.M{"totp_last_used_step": bson.M{"$exists": false}},
+				bson.M{"totp_last_used_step": bson.M{"$lt": step}},
+			},
+		},
+		bson.M{"$set": bson.M{"totp_last_used_step": step, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *userMongoRepository) RemoveRecoveryCode(ctx context.Context, id string, codeHash string) error {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	// Matching on the hash makes the removal fail if a concurrent request already redeemed the code.
+	result, err := r.db.Collection(userCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": objectID, "recovery_codes": codeHash},
+		bson.M{
+			"$pull": bson.M{"recovery_codes": codeHash},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.ModifiedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+)
+
+const (
+	webAuthnCredentialCollection = "webauthn_credentials"
+	webAuthnChallengeCollection  = "webauthn_challenges"
+)
+
+type webAuthnCredentialMongoRepository struct {
+	db *mongo.Database
+}
+
+func NewWebAuthnCredentialMongoRepository(
+	ctx context.Context,
+	logger *zerolog.Logger,
+	db *mongo.Database,
+) domain.WebAuthnCredentialRepository {
+	collection := db.Collection(webAuthnCredentialCollection)
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "credential_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create WebAuthn credential indexes")
+	}
+
+	return &webAuthnCredentialMongoRepository{db: db}
+}
+
+func (r *webAuthnCredentialMongoRepository) CreateCredential(
+	ctx context.Context,
+	credential *domain.WebAuthnCredential,
+) (*domain.WebAuthnCredential, error) {
+	now := time.Now()
+	credential.CreatedAt = now
+	credential.UpdatedAt = now
+
+	result, err := r.db.Collection(webAuthnCredentialCollection).InsertOne(ctx, credential)
+	if err != nil {
+		return nil, err
+	}
+
+	if objectID, ok := result.InsertedID.(bson.ObjectID); ok {
+		credential.ID = objectID
+	} else {
+		return nil, errors.New("failed to convert inserted ID to ObjectID")
+	}
+
+	return credential, nil
+}
+
+func (r *webAuthnCredentialMongoRepository) GetCredentialsByUserID(
+	ctx context.Context,
+	userID string,
+) ([]domain.WebAuthnCredential, error) {
+	cursor, err := r.db.Collection(webAuthnCredentialCollection).Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+
+	var credentials []domain.WebAuthnCredential
+	if err := cursor.All(ctx, &credentials); err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+func (r *webAuthnCredentialMongoRepository) UpdateCredential(
+	ctx context.Context,
+	id string,
+	params domain.UpdateWebAuthnCredentialParams,
+) error {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	updateMap := bson.M{}
+	if params.SignCount != nil {
+		updateMap["sign_count"] = params.SignCount
+	}
+	if params.CloneWarning != nil {
+		updateMap["clone_warning"] = params.CloneWarning
+	}
+	if params.BackupState != nil {
+		updateMap["backup_state"] = params.BackupState
+	}
+	if params.LastUsedAt != nil {
+		updateMap["last_used_at"] = params.LastUsedAt
+	}
+
+	if len(updateMap) == 0 {
+		return errors.New("no WebAuthn credential fields to update")
+	}
+
+	updateMap["updated_at"] = time.Now()
+
+	result, err := r.db.Collection(webAuthnCredentialCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": objectID},
+		bson.M{"$set": updateMap},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+type webAuthnChallengeMongoRepository struct {
+	db *mongo.Database
+}
+
+func NewWebAuthnChallengeMongoRepository(
+	ctx context.Context,
+	logger *zerolog.Logger,
+	db *mongo.Database,
+) domain.WebAuthnChallengeRepository {
+	collection := db.Collection(webAuthnChallengeCollection)
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create WebAuthn challenge indexes")
+	}
+
+	return &webAuthnChallengeMongoRepository{db: db}
+}
+
+func (r *webAuthnChallengeMongoRepository) CreateChallenge(
+	ctx context.Context,
+	challenge *domain.WebAuthnChallenge,
+) (*domain.WebAuthnChallenge, error) {
+	challenge.CreatedAt = time.Now()
+
+	result, err := r.db.Collection(webAuthnChallengeCollection).InsertOne(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	if objectID, ok := result.InsertedID.(bson.ObjectID); ok {
+		challenge.ID = objectID
+	} else {
+		return nil, errors.New("failed to convert inserted ID to ObjectID")
+	}
+
+	return challenge, nil
+}
+
+func (r *webAuthnChallengeMongoRepository) ConsumeChallenge(
+	ctx context.Context,
+	ceremony string,
+	tokenHash string,
+) (*domain.WebAuthnChallenge, error) {
+	// The challenge is deleted as it is read so that a signed response can never be replayed.
+	result := r.db.Collection(webAuthnChallengeCollection).FindOneAndDelete(ctx, bson.M{
+		"ceremony":   ceremony,
+		"token_hash": tokenHash,
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var challenge domain.WebAuthnChallenge
+	if err := result.Decode(&challenge); err != nil {
+		return nil, err
+	}
+
+	return &challenge, nil
+}
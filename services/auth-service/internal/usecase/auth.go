@@ -3,48 +3,106 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 
 	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/config"
 	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
 	authtypes "github.com/vasapolrittideah/optimize-api/services/auth-service/pkg/types"
 	"github.com/vasapolrittideah/optimize-api/shared/auth"
+	"github.com/vasapolrittideah/optimize-api/shared/mailer"
+	"github.com/vasapolrittideah/optimize-api/shared/oauth"
 	"github.com/vasapolrittideah/optimize-api/shared/security"
+	"github.com/vasapolrittideah/optimize-api/shared/utilities"
 )
 
 var (
 	ErrUserAlreadyExists  = errors.New("user already exists")
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrSessionRevoked     = errors.New("session has been revoked")
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
 )
 
 type authUsecase struct {
-	identityRepo   domain.IdentityRepository
-	sessionRepo    domain.SessionRepository
-	userRepo       domain.UserRepository
-	authenticator  auth.Authenticator
-	authServiceCfg *config.AuthServiceConfig
+	logger                 *zerolog.Logger
+	identityRepo           domain.IdentityRepository
+	sessionRepo            domain.SessionRepository
+	userRepo               domain.UserRepository
+	oneTimeTokenRepo       domain.OneTimeTokenRepository
+	loginAttemptRepo       domain.LoginAttemptRepository
+	oauthStateRepo         domain.OAuthStateRepository
+	webAuthnCredentialRepo domain.WebAuthnCredentialRepository
+	webAuthnChallengeRepo  domain.WebAuthnChallengeRepository
+	revokedSessionRepo     domain.RevokedSessionRepository
+	authenticator          auth.Authenticator
+	accessTokenKeys        *auth.KeyManager
+	revocations            *auth.RevocationList
+	oauthProviders         *oauth.Registry
+	webAuthn               *webauthn.WebAuthn
+	mailer                 mailer.Mailer
+	encryptor              *security.Encryptor
+	passwordHasher         *security.PasswordHasher
+	passwordPolicy         *security.PasswordPolicy
+	breachChecker          *security.BreachChecker
+	authServiceCfg         *config.AuthServiceConfig
 }
 
 func NewAuthUsecase(
+	logger *zerolog.Logger,
 	identityRepo domain.IdentityRepository,
 	sessionRepo domain.SessionRepository,
 	userRepo domain.UserRepository,
+	oneTimeTokenRepo domain.OneTimeTokenRepository,
+	loginAttemptRepo domain.LoginAttemptRepository,
+	oauthStateRepo domain.OAuthStateRepository,
+	webAuthnCredentialRepo domain.WebAuthnCredentialRepository,
+	webAuthnChallengeRepo domain.WebAuthnChallengeRepository,
+	revokedSessionRepo domain.RevokedSessionRepository,
 	authenticator auth.Authenticator,
+	accessTokenKeys *auth.KeyManager,
+	revocations *auth.RevocationList,
+	oauthProviders *oauth.Registry,
+	webAuthn *webauthn.WebAuthn,
+	mailer mailer.Mailer,
+	encryptor *security.Encryptor,
+	passwordHasher *security.PasswordHasher,
+	passwordPolicy *security.PasswordPolicy,
+	breachChecker *security.BreachChecker,
 	authServiceCfg *config.AuthServiceConfig,
 ) domain.AuthUsecase {
 	return &authUsecase{
-		identityRepo:   identityRepo,
-		sessionRepo:    sessionRepo,
-		userRepo:       userRepo,
-		authenticator:  authenticator,
-		authServiceCfg: authServiceCfg,
+		logger:                 logger,
+		identityRepo:           identityRepo,
+		sessionRepo:            sessionRepo,
+		userRepo:               userRepo,
+		oneTimeTokenRepo:       oneTimeTokenRepo,
+		loginAttemptRepo:       loginAttemptRepo,
+		oauthStateRepo:         oauthStateRepo,
+		webAuthnCredentialRepo: webAuthnCredentialRepo,
+		webAuthnChallengeRepo:  webAuthnChallengeRepo,
+		revokedSessionRepo:     revokedSessionRepo,
+		authenticator:          authenticator,
+		accessTokenKeys:        accessTokenKeys,
+		revocations:            revocations,
+		oauthProviders:         oauthProviders,
+		webAuthn:               webAuthn,
+		mailer:                 mailer,
+		encryptor:              encryptor,
+		passwordHasher:         passwordHasher,
+		passwordPolicy:         passwordPolicy,
+		breachChecker:          breachChecker,
+		authServiceCfg:         authServiceCfg,
 	}
 }
 
-func (u *authUsecase) SignIn(ctx context.Context, params domain.SignInParams) (*authtypes.Tokens, error) {
+func (u *authUsecase) SignIn(ctx context.Context, params domain.SignInParams) (*domain.SignInResult, error) {
 	user, err := u.userRepo.GetUserByEmail(ctx, params.Email)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -54,29 +112,105 @@ func (u *authUsecase) SignIn(ctx context.Context, params domain.SignInParams) (*
 		return nil, err
 	}
 
-	if ok, err := security.VerifyPassword(params.Password, user.PasswordHash); err != nil {
+	ipAddress, _ := clientDevice(ctx)
+	clientIP := ""
+	if ipAddress != nil {
+		clientIP = *ipAddress
+	}
+
+	if err := u.checkLockout(ctx, user.ID.Hex(), clientIP); err != nil {
+		return nil, err
+	}
+
+	// Users created through an OAuth provider have no password until they set one.
+	if user.PasswordHash == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	if ok, err := u.passwordHasher.Verify(params.Password, user.PasswordHash); err != nil {
 		return nil, err
 	} else if !ok {
+		if err := u.recordFailedSignIn(ctx, user.ID.Hex(), clientIP); err != nil {
+			return nil, err
+		}
+
 		return nil, ErrInvalidCredentials
 	}
 
-	if err := u.identityRepo.UpdateLastLogin(ctx, user.ID.Hex()); err != nil {
+	if err := u.loginAttemptRepo.DeleteLoginAttemptsByUserID(ctx, user.ID.Hex()); err != nil {
+		return nil, err
+	}
+
+	// The plaintext password is only available at sign-in, so this is where legacy hashes
+	// of imported users and hashes created with outdated argon2 parameters are upgraded.
+	if u.passwordHasher.NeedsRehash(user.PasswordHash) {
+		passwordHash, err := u.passwordHasher.Hash(params.Password)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := u.userRepo.UpdateUser(ctx, user.ID.Hex(), domain.UpdateUserParams{
+			PasswordHash: &passwordHash,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if u.authServiceCfg.Verification.RequireVerifiedEmail && !user.Verified {
+		return nil, ErrEmailNotVerified
+	}
+
+	identityID, err := u.emailIdentityID(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	mfaRequired, err := u.requiresMFA(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfaRequired {
+		mfaToken, err := u.generateMFAToken(ctx, user.ID.Hex(), identityID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &domain.SignInResult{MFAToken: mfaToken}, nil
+	}
+
+	tokens, err := u.createAuthSession(ctx, user.ID.Hex())
+	if err != nil {
 		return nil, err
 	}
 
-	return u.createAuthSession(ctx, user.ID.Hex())
+	u.recordLastLogin(ctx, identityID)
+
+	return &domain.SignInResult{Tokens: tokens}, nil
 }
 
-func (u *authUsecase) SignUp(ctx context.Context, params domain.SignUpParams) (*authtypes.Tokens, error) {
-	passwordHash, err := security.HashPassword(params.Password)
+func (u *authUsecase) SignUp(ctx context.Context, params domain.SignUpParams) (*domain.SignUpResult, error) {
+	if err := u.validatePassword(params.Password, params.Email, params.FullName); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := u.passwordHasher.Hash(params.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	verificationCode, verificationCodeHash, verificationCodeExpiresAt, err := u.newVerificationCode()
 	if err != nil {
 		return nil, err
 	}
 
 	user, err := u.userRepo.CreateUser(ctx, &domain.User{
-		Email:        params.Email,
-		FullName:     params.FullName,
-		PasswordHash: passwordHash,
+		Email:                     params.Email,
+		FullName:                  params.FullName,
+		PasswordHash:              passwordHash,
+		VerificationCode:          verificationCodeHash,
+		VerificationCodeExpiresAt: verificationCodeExpiresAt,
+		VerificationCodeSentAt:    time.Now(),
 	})
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
@@ -88,26 +222,266 @@ func (u *authUsecase) SignUp(ctx context.Context, params domain.SignUpParams) (*
 
 	if _, err := u.identityRepo.CreateIdentity(ctx, &domain.Identity{
 		UserID:     user.ID.Hex(),
-		Provider:   "email",
+		Provider:   domain.IdentityProviderEmail,
 		ProviderID: "",
 		Email:      user.Email,
 	}); err != nil {
 		return nil, err
 	}
 
-	return u.createAuthSession(ctx, user.ID.Hex())
+	// The account already exists at this point, so failing here would only make the retry fail
+	// with ErrUserAlreadyExists. The user can ask for the code again with ResendVerification.
+	if err := u.sendVerificationEmail(ctx, user.Email, verificationCode); err != nil {
+		u.logger.Error().Err(err).Str("user_id", user.ID.Hex()).Msg("failed to send verification email")
+	}
+
+	// Anyone can sign up with an address they do not own, so no session is started until the
+	// address is verified when that is required to sign in.
+	if u.authServiceCfg.Verification.RequireVerifiedEmail {
+		return &domain.SignUpResult{VerificationRequired: true}, nil
+	}
+
+	tokens, err := u.createAuthSession(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.SignUpResult{Tokens: tokens}, nil
+}
+
+func (u *authUsecase) RefreshTokens(
+	ctx context.Context,
+	params domain.RefreshTokensParams,
+) (*authtypes.Tokens, error) {
+	claims, err := u.parseToken(
+		params.RefreshToken,
+		auth.NewSecretKeySet(u.authServiceCfg.Token.RefreshTokenSecret),
+		auth.TokenTypeRefresh,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := u.sessionRepo.GetSession(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidToken
+		}
+
+		return nil, err
+	}
+
+	if session.UserID != claims.UserID {
+		return nil, ErrInvalidToken
+	}
+
+	if session.RevokedAt != nil {
+		return nil, ErrSessionRevoked
+	}
+
+	// A validly signed refresh token for a session that has already been rotated
+	// means the token was replayed, so the whole session family is treated as stolen.
+	if session.RotatedAt != nil || !refreshTokenMatches(session, params.RefreshToken) {
+		return nil, u.revokeSessionFamily(ctx, sessionFamilyID(session))
+	}
+
+	if time.Now().After(session.RefreshTokenExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	if _, err := u.sessionRepo.RotateSession(ctx, session.ID.Hex()); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, u.revokeSessionFamily(ctx, sessionFamilyID(session))
+		}
+
+		return nil, err
+	}
+
+	ipAddress, userAgent := clientDevice(ctx)
+	if ipAddress == nil {
+		ipAddress = session.IPAddress
+	}
+	if userAgent == nil {
+		userAgent = session.UserAgent
+	}
+
+	parentID := session.ID.Hex()
+	child, err := u.sessionRepo.CreateSession(ctx, &domain.Session{
+		UserID:          session.UserID,
+		FamilyID:        sessionFamilyID(session),
+		ParentID:        &parentID,
+		Generation:      session.Generation + 1,
+		IPAddress:       ipAddress,
+		UserAgent:       userAgent,
+		AuthenticatedAt: session.AuthenticatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return u.issueTokens(ctx, child.UserID, child.ID.Hex())
+}
+
+func (u *authUsecase) SignOut(ctx context.Context, params domain.SignOutParams) error {
+	claims, err := u.parseToken(params.AccessToken, u.accessTokenKeys, auth.TokenTypeAccess)
+	if err != nil {
+		return err
+	}
+
+	session, err := u.sessionRepo.GetSession(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrInvalidToken
+		}
+
+		return err
+	}
+
+	if session.UserID != claims.UserID {
+		return ErrInvalidToken
+	}
+
+	// The whole family is revoked so that a refresh token issued to a later
+	// generation of the same sign-in cannot keep the session alive.
+	sessions, err := u.sessionRepo.RevokeSessionFamily(ctx, sessionFamilyID(session))
+	if err != nil {
+		return err
+	}
+
+	return u.revokeAccessTokens(ctx, sessions)
+}
+
+func (u *authUsecase) SignOutAll(ctx context.Context, params domain.SignOutAllParams) error {
+	claims, err := u.parseToken(params.AccessToken, u.accessTokenKeys, auth.TokenTypeAccess)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := u.sessionRepo.RevokeSessionsByUserID(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return u.revokeAccessTokens(ctx, sessions)
+}
+
+func (u *authUsecase) ListSessions(
+	ctx context.Context,
+	params domain.ListSessionsParams,
+) ([]*domain.ActiveSession, error) {
+	current, err := u.authenticateSession(ctx, params.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := u.sessionRepo.ListActiveSessionsByUserID(ctx, current.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	activeSessions := make([]*domain.ActiveSession, 0, len(sessions))
+	for _, session := range sessions {
+		activeSession := &domain.ActiveSession{
+			ID:         session.ID.Hex(),
+			CreatedAt:  session.AuthenticatedAt,
+			LastUsedAt: session.CreatedAt,
+			Current:    sessionFamilyID(session) == sessionFamilyID(current),
+		}
+		if session.IPAddress != nil {
+			activeSession.IPAddress = *session.IPAddress
+		}
+		if session.UserAgent != nil {
+			activeSession.UserAgent = *session.UserAgent
+		}
+
+		activeSessions = append(activeSessions, activeSession)
+	}
+
+	return activeSessions, nil
 }
 
 func (u *authUsecase) createAuthSession(ctx context.Context, userID string) (*authtypes.Tokens, error) {
-	session, err := u.sessionRepo.CreateSession(ctx, &domain.Session{UserID: userID})
+	session, err := u.startSession(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.issueTokens(ctx, userID, session.ID.Hex())
+}
+
+// startSession creates the first session of a new session family for the user.
+func (u *authUsecase) startSession(ctx context.Context, userID string) (*domain.Session, error) {
+	ipAddress, userAgent := clientDevice(ctx)
+
+	sessionID := bson.NewObjectID()
+
+	return u.sessionRepo.CreateSession(ctx, &domain.Session{
+		ID:              sessionID,
+		UserID:          userID,
+		FamilyID:        sessionID.Hex(),
+		IPAddress:       ipAddress,
+		UserAgent:       userAgent,
+		AuthenticatedAt: time.Now(),
+	})
+}
+
+// sessionFamilyID returns the family of the session. Sessions created before families were
+// introduced have no family ID, and are the first session of a family of their own.
+func sessionFamilyID(session *domain.Session) string {
+	if session.FamilyID == "" {
+		return session.ID.Hex()
+	}
+
+	return session.FamilyID
+}
+
+// refreshTokenMatches reports whether the refresh token is the one last issued to the session.
+func refreshTokenMatches(session *domain.Session, refreshToken string) bool {
+	storedHash := session.RefreshTokenHash
+	if storedHash == "" {
+		if session.RefreshToken == "" {
+			return false
+		}
+
+		storedHash = security.HashToken(session.RefreshToken)
+	}
+
+	return storedHash == security.HashToken(refreshToken)
+}
+
+// revokeSessionFamily revokes every session derived from the same sign-in after
+// refresh token reuse is detected.
+func (u *authUsecase) revokeSessionFamily(ctx context.Context, familyID string) error {
+	sessions, err := u.sessionRepo.RevokeSessionFamily(ctx, familyID)
+	if err != nil {
+		return err
+	}
+
+	if err := u.revokeAccessTokens(ctx, sessions); err != nil {
+		return err
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// issueTokens generates a new access and refresh token pair for the session. Only the hash of
+// the refresh token is stored, to be compared when it is exchanged.
+// The user is read again on every refresh, so that changes to their roles reach the tokens.
+func (u *authUsecase) issueTokens(ctx context.Context, userID, sessionID string) (*authtypes.Tokens, error) {
+	user, err := u.userRepo.GetUser(ctx, userID)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidToken
+		}
+
 		return nil, err
 	}
 
 	accessToken, err := u.generateToken(
-		userID,
-		session.ID.Hex(),
-		u.authServiceCfg.Token.AccessTokenSecret,
+		user,
+		sessionID,
+		auth.TokenTypeAccess,
+		u.accessTokenKeys,
 		u.authServiceCfg.Token.AccessTokenExpiresIn,
 	)
 	if err != nil {
@@ -115,9 +489,10 @@ func (u *authUsecase) createAuthSession(ctx context.Context, userID string) (*au
 	}
 
 	refreshToken, err := u.generateToken(
-		userID,
-		session.ID.Hex(),
-		u.authServiceCfg.Token.RefreshTokenSecret,
+		user,
+		sessionID,
+		auth.TokenTypeRefresh,
+		auth.NewSecretKeySet(u.authServiceCfg.Token.RefreshTokenSecret),
 		u.authServiceCfg.Token.RefreshTokenExpiresIn,
 	)
 	if err != nil {
@@ -125,9 +500,8 @@ func (u *authUsecase) createAuthSession(ctx context.Context, userID string) (*au
 	}
 
 	now := time.Now()
-	if _, err := u.sessionRepo.UpdateTokens(ctx, session.ID.Hex(), domain.UpdateTokensParams{
-		AccessToken:           accessToken,
-		RefreshToken:          refreshToken,
+	if _, err := u.sessionRepo.UpdateTokens(ctx, sessionID, domain.UpdateTokensParams{
+		RefreshTokenHash:      security.HashToken(refreshToken),
 		AccessTokenExpiresAt:  now.Add(u.authServiceCfg.Token.AccessTokenExpiresIn),
 		RefreshTokenExpiresAt: now.Add(u.authServiceCfg.Token.RefreshTokenExpiresIn),
 	}); err != nil {
@@ -140,11 +514,25 @@ func (u *authUsecase) createAuthSession(ctx context.Context, userID string) (*au
 	}, nil
 }
 
-func (u *authUsecase) generateToken(userID, sessionID, secret string, expiresIn time.Duration) (string, error) {
+func (u *authUsecase) generateToken(
+	user *domain.User,
+	sessionID string,
+	tokenType string,
+	keys auth.KeyProvider,
+	expiresIn time.Duration,
+) (string, error) {
+	roles := user.Roles
+	if len(roles) == 0 {
+		roles = []string{domain.RoleUser}
+	}
+
 	now := time.Now()
 	claims := authtypes.JWTClaims{
-		UserID:    userID,
+		UserID:    user.ID.Hex(),
 		SessionID: sessionID,
+		TokenType: tokenType,
+		Roles:     roles,
+		Scope:     u.roleScope(roles),
 		RegisteredClaims: jwt.RegisteredClaims{
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
@@ -153,10 +541,97 @@ func (u *authUsecase) generateToken(userID, sessionID, secret string, expiresIn
 			Audience:  jwt.ClaimStrings{u.authServiceCfg.Token.Issuer},
 		},
 	}
-	token, err := u.authenticator.GenerateToken(claims, secret)
+	token, err := u.authenticator.GenerateToken(claims, keys)
 	if err != nil {
 		return "", err
 	}
 
 	return token, nil
 }
+
+// authenticateSession validates the access token and returns the live session it was issued for.
+func (u *authUsecase) authenticateSession(ctx context.Context, accessToken string) (*domain.Session, error) {
+	claims, err := u.parseToken(accessToken, u.accessTokenKeys, auth.TokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.liveSession(ctx, claims)
+}
+
+// liveSession returns the session that the token claims were issued for, as long as it
+// still belongs to the user and has not been revoked.
+func (u *authUsecase) liveSession(ctx context.Context, claims *authtypes.JWTClaims) (*domain.Session, error) {
+	session, err := u.sessionRepo.GetSession(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidToken
+		}
+
+		return nil, err
+	}
+
+	if session.UserID != claims.UserID {
+		return nil, ErrInvalidToken
+	}
+
+	if session.RevokedAt != nil {
+		return nil, ErrSessionRevoked
+	}
+
+	return session, nil
+}
+
+// clientDevice returns the client IP address and user agent forwarded with the request, if any.
+func clientDevice(ctx context.Context) (*string, *string) {
+	clientMetadata := utilities.ClientMetadataFromContext(ctx)
+
+	var ipAddress, userAgent *string
+	if clientMetadata.IPAddress != "" {
+		ipAddress = &clientMetadata.IPAddress
+	}
+	if clientMetadata.UserAgent != "" {
+		userAgent = &clientMetadata.UserAgent
+	}
+
+	return ipAddress, userAgent
+}
+
+// parseToken validates the token with the given keys and extracts its claims, rejecting tokens
+// of another type.
+func (u *authUsecase) parseToken(
+	token string,
+	keys auth.KeyProvider,
+	tokenType string,
+) (*authtypes.JWTClaims, error) {
+	claims, err := auth.ParseClaims[authtypes.JWTClaims](u.authenticator, token, keys)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if claims.UserID == "" || claims.SessionID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	// Tokens issued before token types were introduced carry none. They are still told apart
+	// by the keys they are signed with, until the cutoff after which every token has a type.
+	if claims.TokenType == "" {
+		if claims.IssuedAt == nil || !claims.IssuedAt.Before(u.authServiceCfg.Token.TokenTypeRequiredAfter) {
+			return nil, ErrInvalidToken
+		}
+	} else if claims.TokenType != tokenType {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// roleScope returns the scope claim granting the scopes of every one of the roles.
+func (u *authUsecase) roleScope(roles []string) string {
+	var scopes []string
+	for _, role := range roles {
+		scopes = append(scopes, auth.ParseScope(u.authServiceCfg.Authorization.RoleScopes[role])...)
+	}
+
+	return auth.FormatScope(scopes)
+}
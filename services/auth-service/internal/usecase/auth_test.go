@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	authtypes "github.com/vasapolrittideah/optimize-api/services/auth-service/pkg/types"
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
+)
+
+func TestParseToken_AcceptsUntypedTokensOnlyBeforeCutoff(t *testing.T) {
+	u := newTestUsecase(t, nil)
+	impl := u.AuthUsecase.(*authUsecase)
+
+	cutoff := time.Now().Add(-time.Hour).Truncate(time.Second)
+	u.authServiceCfg.Token.TokenTypeRequiredAfter = cutoff
+
+	untypedToken := func(issuedAt *jwt.NumericDate) string {
+		t.Helper()
+
+		token, err := u.authenticator.GenerateToken(authtypes.JWTClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				IssuedAt:  issuedAt,
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+				Issuer:    testIssuer,
+				Audience:  jwt.ClaimStrings{testIssuer},
+			},
+			UserID:    "user-id",
+			SessionID: "session-id",
+		}, u.accessTokenKeys)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+
+		return token
+	}
+
+	tests := []struct {
+		name     string
+		issuedAt *jwt.NumericDate
+		wantErr  error
+	}{
+		{name: "issued before the cutoff", issuedAt: jwt.NewNumericDate(cutoff.Add(-time.Minute))},
+		{name: "issued at the cutoff", issuedAt: jwt.NewNumericDate(cutoff), wantErr: ErrInvalidToken},
+		{name: "issued after the cutoff", issuedAt: jwt.NewNumericDate(time.Now()), wantErr: ErrInvalidToken},
+		{name: "without an issue time", wantErr: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := impl.parseToken(untypedToken(tt.issuedAt), u.accessTokenKeys, auth.TokenTypeAccess)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("parseToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Without a cutoff, no untyped token is accepted, however old.
+	u.authServiceCfg.Token.TokenTypeRequiredAfter = time.Time{}
+	oldToken := untypedToken(jwt.NewNumericDate(cutoff.Add(-24 * time.Hour)))
+	if _, err := impl.parseToken(oldToken, u.accessTokenKeys, auth.TokenTypeAccess); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("parseToken() without a cutoff error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestRefreshTokens_KeepsLegacySessionsInFamiliesOfTheirOwn(t *testing.T) {
+	u := newTestUsecase(t, nil)
+	impl := u.AuthUsecase.(*authUsecase)
+
+	user := u.createUser(t, "user@example.com")
+	other := u.createUser(t, "other@example.com")
+
+	tokens, err := impl.createAuthSession(t.Context(), user.ID.Hex())
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	otherTokens, err := impl.createAuthSession(t.Context(), other.ID.Hex())
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	sessionID := u.accessTokenClaims(t, tokens.AccessToken).SessionID
+	otherSessionID := u.accessTokenClaims(t, otherTokens.AccessToken).SessionID
+	u.sessions.legacySession(t, sessionID, tokens.RefreshToken)
+	u.sessions.legacySession(t, otherSessionID, otherTokens.RefreshToken)
+
+	// The plaintext refresh token of a legacy session is still accepted once.
+	rotated, err := u.RefreshTokens(t.Context(), domain.RefreshTokensParams{RefreshToken: tokens.RefreshToken})
+	if err != nil {
+		t.Fatalf("RefreshTokens() error = %v", err)
+	}
+
+	child, err := u.sessions.GetSession(t.Context(), u.accessTokenClaims(t, rotated.AccessToken).SessionID)
+	if err != nil {
+		t.Fatalf("rotated session not found: %v", err)
+	}
+	if child.FamilyID != sessionID || child.RefreshToken != "" || child.RefreshTokenHash == "" {
+		t.Errorf("rotated session = %+v, want family %s and only a refresh token hash", child, sessionID)
+	}
+
+	// Replaying the legacy token revokes its own family, not every other legacy session.
+	if _, err := u.RefreshTokens(t.Context(), domain.RefreshTokensParams{
+		RefreshToken: tokens.RefreshToken,
+	}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("RefreshTokens() with a reused token error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	if child, _ = u.sessions.GetSession(t.Context(), child.ID.Hex()); child.RevokedAt == nil {
+		t.Error("rotated session of the reused token was not revoked")
+	}
+	if otherSession, _ := u.sessions.GetSession(t.Context(), otherSessionID); otherSession.RevokedAt != nil {
+		t.Error("legacy session of another user was revoked")
+	}
+}
+
+func TestSignUp_StartsNoSessionUntilEmailIsVerified(t *testing.T) {
+	u := newTestUsecase(t, nil)
+	u.authServiceCfg.Verification.RequireVerifiedEmail = true
+
+	result, err := u.SignUp(t.Context(), domain.SignUpParams{
+		Email:    "new@example.com",
+		Password: "correct horse battery staple",
+		FullName: "New User",
+	})
+	if err != nil {
+		t.Fatalf("SignUp() error = %v", err)
+	}
+	if result.Tokens != nil || !result.VerificationRequired {
+		t.Fatalf("SignUp() = %+v, want verification required and no tokens", result)
+	}
+
+	user, err := u.users.GetUserByEmail(t.Context(), "new@example.com")
+	if err != nil {
+		t.Fatalf("user was not created: %v", err)
+	}
+	if sessions, _ := u.sessions.ListActiveSessionsByUserID(t.Context(), user.ID.Hex()); len(sessions) != 0 {
+		t.Errorf("got %d sessions for an unverified user, want none", len(sessions))
+	}
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/vasapolrittideah/optimize-api/shared/mailer"
+)
+
+// sendInBackground sends the email without waiting for the mail server, so that requests for
+// registered addresses take no longer than requests for unknown ones. Failures are logged.
+func (u *authUsecase) sendInBackground(ctx context.Context, userID string, message *mailer.Message) {
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		if err := u.mailer.Send(ctx, message); err != nil {
+			u.logger.Error().Err(err).Str("user_id", userID).Str("subject", message.Subject).Msg("failed to send email")
+		}
+	}()
+}
+
+// newVerificationEmail builds the email that delivers an email verification code.
+func newVerificationEmail(to, code string, expiresIn time.Duration) *mailer.Message {
+	return &mailer.Message{
+		To:      []string{to},
+		Subject: "Verify your email address",
+		TextBody: fmt.Sprintf(
+			"Your verification code is %s.\n\nThe code expires in %s. "+
+				"If you did not create an account, you can ignore this email.\n",
+			code,
+			expiresIn,
+		),
+	}
+}
+
+// newPasswordResetEmail builds the email that delivers a password reset link.
+func newPasswordResetEmail(to, resetURL string, expiresIn time.Duration) *mailer.Message {
+	return &mailer.Message{
+		To:      []string{to},
+		Subject: "Reset your password",
+		TextBody: fmt.Sprintf(
+			"Use the link below to choose a new password:\n\n%s\n\nThe link expires in %s. "+
+				"If you did not request a password reset, you can ignore this email.\n",
+			resetURL,
+			expiresIn,
+		),
+	}
+}
+
+// newMagicLinkEmail builds the email that delivers a passwordless sign-in link.
+func newMagicLinkEmail(to, magicLinkURL string, expiresIn time.Duration) *mailer.Message {
+	return &mailer.Message{
+		To:      []string{to},
+		Subject: "Your sign-in link",
+		TextBody: fmt.Sprintf(
+			"Use the link below to sign in:\n\n%s\n\nThe link expires in %s and can only be used once. "+
+				"If you did not request it, you can ignore this email.\n",
+			magicLinkURL,
+			expiresIn,
+		),
+	}
+}
+
+// withToken returns the link with the token appended as a query parameter.
+func withToken(link, token string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
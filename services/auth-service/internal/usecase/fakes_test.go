@@ -0,0 +1,1013 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/config"
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
+	"github.com/vasapolrittideah/optimize-api/shared/mailer"
+	"github.com/vasapolrittideah/optimize-api/shared/oauth"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
+)
+
+const testIssuer = "https://auth.example.com"
+
+// duplicateKeyError is the error returned by the fake repositories for a violated unique index.
+var duplicateKeyError = mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}
+
+// testUsecase holds an auth usecase backed by in-memory repositories, along with the
+// repositories so that tests can inspect and seed them.
+type testUsecase struct {
+	domain.AuthUsecase
+
+	identities        *fakeIdentityRepository
+	sessions          *fakeSessionRepository
+	users             *fakeUserRepository
+	oneTimeTokens     *fakeOneTimeTokenRepository
+	oauthStates       *fakeOAuthStateRepository
+	passkeys          *fakeWebAuthnCredentialRepository
+	passkeyChallenges *fakeWebAuthnChallengeRepository
+	revokedSessions   *fakeRevokedSessionRepository
+	accessTokenKeys   *auth.KeyManager
+	authenticator     auth.Authenticator
+	authServiceCfg    *config.AuthServiceConfig
+	encryptor         *security.Encryptor
+	passwordHasher    *security.PasswordHasher
+	mailer            *mailer.MemoryMailer
+}
+
+// newTestUsecase creates an auth usecase backed by in-memory repositories. The WebAuthn
+// relying party and the OAuth providers are optional.
+func newTestUsecase(t *testing.T, webAuthn *webauthn.WebAuthn, providers ...oauth.Provider) *testUsecase {
+	t.Helper()
+
+	logger := zerolog.Nop()
+
+	encryptor, err := security.NewEncryptor(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+	if err != nil {
+		t.Fatalf("failed to create encryptor: %v", err)
+	}
+
+	accessTokenKeys := auth.NewKeyManager(nil, time.Hour)
+	if err := accessTokenKeys.Bootstrap(t.Context(), auth.NewSecretKeySet("access-token-secret")); err != nil {
+		t.Fatalf("failed to load access token keys: %v", err)
+	}
+
+	cfg := &config.AuthServiceConfig{
+		Token: config.TokenConfig{
+			RefreshTokenSecret:    "refresh-token-secret",
+			AccessTokenExpiresIn:  15 * time.Minute,
+			RefreshTokenExpiresIn: 24 * time.Hour,
+			Issuer:                testIssuer,
+		},
+		MFA: config.MFAConfig{
+			ChallengeSecret:    "mfa-challenge-secret",
+			ChallengeExpiresIn: 5 * time.Minute,
+			MaxAttempts:        5,
+		},
+		OAuth: config.OAuthConfig{
+			StateExpiresIn: 10 * time.Minute,
+		},
+		WebAuthn: config.WebAuthnConfig{
+			ChallengeExpiresIn: 5 * time.Minute,
+		},
+		Authorization: config.AuthorizationConfig{
+			RoleScopes: map[string]string{domain.RoleUser: "profile sessions"},
+		},
+	}
+
+	u := &testUsecase{
+		identities:        &fakeIdentityRepository{},
+		sessions:          &fakeSessionRepository{},
+		users:             &fakeUserRepository{},
+		oneTimeTokens:     &fakeOneTimeTokenRepository{},
+		oauthStates:       &fakeOAuthStateRepository{},
+		passkeys:          &fakeWebAuthnCredentialRepository{},
+		passkeyChallenges: &fakeWebAuthnChallengeRepository{},
+		revokedSessions:   &fakeRevokedSessionRepository{},
+		accessTokenKeys:   accessTokenKeys,
+		authenticator:     auth.NewJWTAuthenticator(testIssuer, testIssuer, nil),
+		authServiceCfg:    cfg,
+		encryptor:         encryptor,
+		passwordHasher:    &security.PasswordHasher{MemoryCost: 1024, TimeCost: 1, Parallelism: 1},
+		mailer:            mailer.NewMemoryMailer(),
+	}
+
+	u.AuthUsecase = NewAuthUsecase(
+		&logger,
+		u.identities,
+		u.sessions,
+		u.users,
+		u.oneTimeTokens,
+		&fakeLoginAttemptRepository{},
+		u.oauthStates,
+		u.passkeys,
+		u.passkeyChallenges,
+		u.revokedSessions,
+		u.authenticator,
+		u.accessTokenKeys,
+		auth.NewRevocationList(NewRevocationSource(u.revokedSessions)),
+		oauth.NewRegistry(providers...),
+		webAuthn,
+		u.mailer,
+		u.encryptor,
+		u.passwordHasher,
+		&security.PasswordPolicy{MinLength: 8, MaxLength: 128},
+		&security.BreachChecker{},
+		cfg,
+	)
+
+	return u
+}
+
+// createUser stores a verified user with the given email address.
+func (u *testUsecase) createUser(t *testing.T, email string) *domain.User {
+	t.Helper()
+
+	user, err := u.users.CreateUser(t.Context(), &domain.User{
+		Email:    email,
+		FullName: "Test User",
+		Verified: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return user
+}
+
+// enableTOTP enrolls an authenticator for the user and returns its secret.
+func (u *testUsecase) enableTOTP(t *testing.T, userID string) string {
+	t.Helper()
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("failed to generate TOTP secret: %v", err)
+	}
+
+	encryptedSecret, err := u.encryptor.Encrypt(secret)
+	if err != nil {
+		t.Fatalf("failed to encrypt TOTP secret: %v", err)
+	}
+
+	enabled := true
+	if _, err := u.users.UpdateUser(t.Context(), userID, domain.UpdateUserParams{
+		TOTPSecret:  &encryptedSecret,
+		TOTPEnabled: &enabled,
+	}); err != nil {
+		t.Fatalf("failed to enable TOTP: %v", err)
+	}
+
+	return secret
+}
+
+// signIn starts a session for the user and returns its access token.
+func (u *testUsecase) signIn(t *testing.T, userID string) string {
+	t.Helper()
+
+	impl := u.AuthUsecase.(*authUsecase)
+	tokens, err := impl.createAuthSession(t.Context(), userID)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	return tokens.AccessToken
+}
+
+// accessTokenClaims validates the access token and returns its claims.
+func (u *testUsecase) accessTokenClaims(t *testing.T, accessToken string) *accessTokenClaims {
+	t.Helper()
+
+	claims, err := auth.ParseClaims[accessTokenClaims](u.authenticator, accessToken, u.accessTokenKeys)
+	if err != nil {
+		t.Fatalf("failed to parse access token: %v", err)
+	}
+
+	return claims
+}
+
+type accessTokenClaims struct {
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+	TokenType string `json:"token_type"`
+}
+
+type fakeIdentityRepository struct {
+	mu         sync.Mutex
+	identities []domain.Identity
+
+	// createErr, when set, is returned by CreateIdentity instead of storing the identity.
+	createErr error
+}
+
+func (r *fakeIdentityRepository) CreateIdentity(
+	_ context.Context,
+	identity *domain.Identity,
+) (*domain.Identity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.createErr != nil {
+		return nil, r.createErr
+	}
+
+	for _, existing := range r.identities {
+		if existing.Provider == identity.Provider && existing.ProviderID == identity.ProviderID {
+			return nil, duplicateKeyError
+		}
+	}
+
+	identity.ID = bson.NewObjectID()
+	identity.CreatedAt = time.Now()
+	identity.UpdatedAt = identity.CreatedAt
+	r.identities = append(r.identities, *identity)
+
+	return identity, nil
+}
+
+func (r *fakeIdentityRepository) GetIdentitiesByUserID(_ context.Context, userID string) ([]domain.Identity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var identities []domain.Identity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+
+	return identities, nil
+}
+
+func (r *fakeIdentityRepository) GetIdentityByProvider(
+	_ context.Context,
+	providerID string,
+	provider string,
+) (*domain.Identity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.ProviderID == providerID {
+			return &identity, nil
+		}
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeIdentityRepository) UpdateLastLogin(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.identities {
+		if r.identities[i].ID.Hex() == id {
+			r.identities[i].LastLoginAt = time.Now()
+			return nil
+		}
+	}
+
+	return mongo.ErrNoDocuments
+}
+
+func (r *fakeIdentityRepository) DeleteIdentity(_ context.Context, id, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, identity := range r.identities {
+		if identity.ID.Hex() == id && identity.UserID == userID {
+			r.identities = slices.Delete(r.identities, i, i+1)
+			return nil
+		}
+	}
+
+	return mongo.ErrNoDocuments
+}
+
+type fakeSessionRepository struct {
+	mu       sync.Mutex
+	sessions []*domain.Session
+}
+
+func (r *fakeSessionRepository) CreateSession(_ context.Context, session *domain.Session) (*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session.ID.IsZero() {
+		session.ID = bson.NewObjectID()
+	}
+	session.CreatedAt = time.Now()
+	session.UpdatedAt = session.CreatedAt
+
+	stored := *session
+	r.sessions = append(r.sessions, &stored)
+
+	return session, nil
+}
+
+func (r *fakeSessionRepository) GetSession(_ context.Context, id string) (*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, session := range r.sessions {
+		if session.ID.Hex() == id {
+			found := *session
+			return &found, nil
+		}
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeSessionRepository) GetSessionByUserID(_ context.Context, userID string) (*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			found := *session
+			return &found, nil
+		}
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeSessionRepository) ListActiveSessionsByUserID(
+	_ context.Context,
+	userID string,
+) ([]*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sessions []*domain.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.RotatedAt == nil {
+			found := *session
+			sessions = append(sessions, &found)
+		}
+	}
+
+	return sessions, nil
+}
+
+func (r *fakeSessionRepository) UpdateTokens(
+	_ context.Context,
+	id string,
+	params domain.UpdateTokensParams,
+) (*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, session := range r.sessions {
+		if session.ID.Hex() == id {
+			session.RefreshTokenHash = params.RefreshTokenHash
+			session.RefreshToken = ""
+			session.AccessTokenExpiresAt = params.AccessTokenExpiresAt
+			session.RefreshTokenExpiresAt = params.RefreshTokenExpiresAt
+			session.UpdatedAt = time.Now()
+
+			updated := *session
+			return &updated, nil
+		}
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeSessionRepository) RotateSession(_ context.Context, id string) (*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, session := range r.sessions {
+		if session.ID.Hex() == id && session.RotatedAt == nil && session.RevokedAt == nil {
+			now := time.Now()
+			session.RotatedAt = &now
+
+			rotated := *session
+			return &rotated, nil
+		}
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeSessionRepository) RevokeSessionFamily(_ context.Context, familyID string) ([]*domain.Session, error) {
+	if familyID == "" {
+		return nil, errors.New("session family ID is empty")
+	}
+
+	return r.revoke(func(session *domain.Session) bool {
+		return session.FamilyID == familyID || session.ID.Hex() == familyID
+	}), nil
+}
+
+func (r *fakeSessionRepository) RevokeSessionsByUserID(_ context.Context, userID string) ([]*domain.Session, error) {
+	return r.revoke(func(session *domain.Session) bool {
+		return session.UserID == userID
+	}), nil
+}
+
+func (r *fakeSessionRepository) RevokeOtherSessions(
+	_ context.Context,
+	userID string,
+	keepFamilyID string,
+) ([]*domain.Session, error) {
+	if keepFamilyID == "" {
+		return nil, errors.New("session family ID is empty")
+	}
+
+	return r.revoke(func(session *domain.Session) bool {
+		return session.UserID == userID && session.FamilyID != keepFamilyID && session.ID.Hex() != keepFamilyID
+	}), nil
+}
+
+// legacySession turns the session into one stored before session families and refresh token
+// hashes were introduced.
+func (r *fakeSessionRepository) legacySession(t *testing.T, sessionID, refreshToken string) {
+	t.Helper()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, session := range r.sessions {
+		if session.ID.Hex() == sessionID {
+			session.FamilyID = ""
+			session.RefreshTokenHash = ""
+			session.RefreshToken = refreshToken
+			return
+		}
+	}
+
+	t.Fatalf("session %s not found", sessionID)
+}
+func (r *fakeSessionRepository) revoke(match func(*domain.Session) bool) []*domain.Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+
+	var revoked []*domain.Session
+	for _, session := range r.sessions {
+		if session.RevokedAt == nil && match(session) {
+			session.RevokedAt = &now
+
+			found := *session
+			revoked = append(revoked, &found)
+		}
+	}
+
+	return revoked
+}
+
+type fakeUserRepository struct {
+	mu    sync.Mutex
+	users []*domain.User
+}
+
+func (r *fakeUserRepository) CreateUser(_ context.Context, user *domain.User) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Email == user.Email {
+			return nil, duplicateKeyError
+		}
+	}
+
+	user.ID = bson.NewObjectID()
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+
+	stored := *user
+	r.users = append(r.users, &stored)
+
+	return user, nil
+}
+
+func (r *fakeUserRepository) GetUser(_ context.Context, id string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user := r.find(id); user != nil {
+		found := *user
+		return &found, nil
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeUserRepository) GetUserByEmail(_ context.Context, email string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			found := *user
+			return &found, nil
+		}
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeUserRepository) UpdateUser(
+	_ context.Context,
+	id string,
+	params domain.UpdateUserParams,
+) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.find(id)
+	if user == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	setIfNotNil(&user.Email, params.Email)
+	setIfNotNil(&user.FullName, params.FullName)
+	setIfNotNil(&user.PasswordHash, params.PasswordHash)
+	setIfNotNil(&user.Verified, params.Verified)
+	setIfNotNil(&user.VerificationCode, params.VerificationCode)
+	setIfNotNil(&user.VerificationCodeExpiresAt, params.VerificationCodeExpiresAt)
+	setIfNotNil(&user.VerificationCodeSentAt, params.VerificationCodeSentAt)
+	setIfNotNil(&user.VerificationAttempts, params.VerificationAttempts)
+	setIfNotNil(&user.TOTPSecret, params.TOTPSecret)
+	setIfNotNil(&user.TOTPEnabled, params.TOTPEnabled)
+	setIfNotNil(&user.TOTPLastUsedStep, params.TOTPLastUsedStep)
+	setIfNotNil(&user.RecoveryCodes, params.RecoveryCodes)
+	setIfNotNil(&user.PasswordChangeRequired, params.PasswordChangeRequired)
+	setIfNotNil(&user.RecoveryFamilyID, params.RecoveryFamilyID)
+	setIfNotNil(&user.Roles, params.Roles)
+	user.UpdatedAt = time.Now()
+
+	updated := *user
+	return &updated, nil
+}
+
+func (r *fakeUserRepository) DeleteUser(_ context.Context, id string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, user := range r.users {
+		if user.ID.Hex() == id {
+			r.users = slices.Delete(r.users, i, i+1)
+			return user, nil
+		}
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeUserRepository) ListUsers(_ context.Context, _ domain.FilterUsersParams) ([]*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := make([]*domain.User, 0, len(r.users))
+	for _, user := range r.users {
+		found := *user
+		users = append(users, &found)
+	}
+
+	return users, nil
+}
+
+func (r *fakeUserRepository) IncrementVerificationAttempts(_ context.Context, id string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.find(id)
+	if user == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	user.VerificationAttempts++
+
+	updated := *user
+	return &updated, nil
+}
+
+func (r *fakeUserRepository) UseTOTPStep(_ context.Context, id string, step int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.find(id)
+	if user == nil || user.TOTPLastUsedStep >= step {
+		return mongo.ErrNoDocuments
+	}
+
+	user.TOTPLastUsedStep = step
+
+	return nil
+}
+
+func (r *fakeUserRepository) RemoveRecoveryCode(_ context.Context, id string, codeHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.find(id)
+	if user == nil || !slices.Contains(user.RecoveryCodes, codeHash) {
+		return mongo.ErrNoDocuments
+	}
+
+	user.RecoveryCodes = slices.DeleteFunc(user.RecoveryCodes, func(hash string) bool {
+		return hash == codeHash
+	})
+
+	return nil
+}
+
+func (r *fakeUserRepository) find(id string) *domain.User {
+	for _, user := range r.users {
+		if user.ID.Hex() == id {
+			return user
+		}
+	}
+
+	return nil
+}
+
+func setIfNotNil[T any](field *T, value *T) {
+	if value != nil {
+		*field = *value
+	}
+}
+
+type fakeOneTimeTokenRepository struct {
+	mu     sync.Mutex
+	tokens []*domain.OneTimeToken
+}
+
+func (r *fakeOneTimeTokenRepository) CreateToken(
+	_ context.Context,
+	token *domain.OneTimeToken,
+) (*domain.OneTimeToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token.ID = bson.NewObjectID()
+	token.CreatedAt = time.Now()
+
+	stored := *token
+	r.tokens = append(r.tokens, &stored)
+
+	return token, nil
+}
+
+func (r *fakeOneTimeTokenRepository) GetToken(
+	_ context.Context,
+	purpose string,
+	tokenHash string,
+) (*domain.OneTimeToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if token := r.find(purpose, tokenHash); token != nil {
+		found := *token
+		return &found, nil
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeOneTimeTokenRepository) ConsumeToken(
+	_ context.Context,
+	purpose string,
+	tokenHash string,
+) (*domain.OneTimeToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token := r.find(purpose, tokenHash)
+	if token == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	now := time.Now()
+	token.ConsumedAt = &now
+
+	consumed := *token
+	return &consumed, nil
+}
+
+func (r *fakeOneTimeTokenRepository) IncrementTokenAttempts(
+	_ context.Context,
+	purpose string,
+	tokenHash string,
+) (*domain.OneTimeToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token := r.find(purpose, tokenHash)
+	if token == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	token.Attempts++
+
+	updated := *token
+	return &updated, nil
+}
+
+func (r *fakeOneTimeTokenRepository) DeleteTokensByUserID(_ context.Context, userID, purpose string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens = slices.DeleteFunc(r.tokens, func(token *domain.OneTimeToken) bool {
+		return token.UserID == userID && token.Purpose == purpose
+	})
+
+	return nil
+}
+
+// find returns the token with the given hash, as long as it is neither consumed nor expired.
+func (r *fakeOneTimeTokenRepository) find(purpose, tokenHash string) *domain.OneTimeToken {
+	for _, token := range r.tokens {
+		if token.Purpose == purpose && token.TokenHash == tokenHash &&
+			token.ConsumedAt == nil && time.Now().Before(token.ExpiresAt) {
+			return token
+		}
+	}
+
+	return nil
+}
+
+type fakeLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts []*domain.LoginAttempt
+}
+
+func (r *fakeLoginAttemptRepository) GetLoginAttempt(
+	_ context.Context,
+	userID string,
+	ipAddress string,
+) (*domain.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, attempt := range r.attempts {
+		if attempt.UserID == userID && attempt.IPAddress == ipAddress {
+			found := *attempt
+			return &found, nil
+		}
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeLoginAttemptRepository) RecordFailedAttempt(
+	_ context.Context,
+	userID string,
+	ipAddress string,
+	expiresAt time.Time,
+) (*domain.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, attempt := range r.attempts {
+		if attempt.UserID == userID && attempt.IPAddress == ipAddress {
+			attempt.Failures++
+			attempt.LastFailedAt = time.Now()
+			attempt.ExpiresAt = expiresAt
+
+			found := *attempt
+			return &found, nil
+		}
+	}
+
+	attempt := &domain.LoginAttempt{
+		ID:           bson.NewObjectID(),
+		UserID:       userID,
+		IPAddress:    ipAddress,
+		Failures:     1,
+		LastFailedAt: time.Now(),
+		ExpiresAt:    expiresAt,
+	}
+	r.attempts = append(r.attempts, attempt)
+
+	found := *attempt
+	return &found, nil
+}
+
+func (r *fakeLoginAttemptRepository) LockLoginAttempt(_ context.Context, id string, lockedUntil time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, attempt := range r.attempts {
+		if attempt.ID.Hex() == id {
+			attempt.LockedUntil = &lockedUntil
+			return nil
+		}
+	}
+
+	return mongo.ErrNoDocuments
+}
+
+func (r *fakeLoginAttemptRepository) DeleteLoginAttemptsByUserID(_ context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attempts = slices.DeleteFunc(r.attempts, func(attempt *domain.LoginAttempt) bool {
+		return attempt.UserID == userID
+	})
+
+	return nil
+}
+
+type fakeOAuthStateRepository struct {
+	mu     sync.Mutex
+	states []*domain.OAuthState
+}
+
+func (r *fakeOAuthStateRepository) CreateState(
+	_ context.Context,
+	state *domain.OAuthState,
+) (*domain.OAuthState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state.ID = bson.NewObjectID()
+	state.CreatedAt = time.Now()
+
+	stored := *state
+	r.states = append(r.states, &stored)
+
+	return state, nil
+}
+
+func (r *fakeOAuthStateRepository) ConsumeState(
+	_ context.Context,
+	provider string,
+	stateHash string,
+) (*domain.OAuthState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, state := range r.states {
+		if state.Provider == provider && state.StateHash == stateHash && time.Now().Before(state.ExpiresAt) {
+			r.states = slices.Delete(r.states, i, i+1)
+			return state, nil
+		}
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+type fakeWebAuthnCredentialRepository struct {
+	mu          sync.Mutex
+	credentials []*domain.WebAuthnCredential
+}
+
+func (r *fakeWebAuthnCredentialRepository) CreateCredential(
+	_ context.Context,
+	credential *domain.WebAuthnCredential,
+) (*domain.WebAuthnCredential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.credentials {
+		if bytes.Equal(existing.CredentialID, credential.CredentialID) {
+			return nil, duplicateKeyError
+		}
+	}
+
+	credential.ID = bson.NewObjectID()
+	credential.CreatedAt = time.Now()
+	credential.UpdatedAt = credential.CreatedAt
+
+	stored := *credential
+	r.credentials = append(r.credentials, &stored)
+
+	return credential, nil
+}
+
+func (r *fakeWebAuthnCredentialRepository) GetCredentialsByUserID(
+	_ context.Context,
+	userID string,
+) ([]domain.WebAuthnCredential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var credentials []domain.WebAuthnCredential
+	for _, credential := range r.credentials {
+		if credential.UserID == userID {
+			credentials = append(credentials, *credential)
+		}
+	}
+
+	return credentials, nil
+}
+
+func (r *fakeWebAuthnCredentialRepository) UpdateCredential(
+	_ context.Context,
+	id string,
+	params domain.UpdateWebAuthnCredentialParams,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, credential := range r.credentials {
+		if credential.ID.Hex() == id {
+			setIfNotNil(&credential.SignCount, params.SignCount)
+			setIfNotNil(&credential.CloneWarning, params.CloneWarning)
+			setIfNotNil(&credential.BackupState, params.BackupState)
+			if params.LastUsedAt != nil {
+				credential.LastUsedAt = params.LastUsedAt
+			}
+			credential.UpdatedAt = time.Now()
+
+			return nil
+		}
+	}
+
+	return mongo.ErrNoDocuments
+}
+
+type fakeWebAuthnChallengeRepository struct {
+	mu         sync.Mutex
+	challenges []*domain.WebAuthnChallenge
+}
+
+func (r *fakeWebAuthnChallengeRepository) CreateChallenge(
+	_ context.Context,
+	challenge *domain.WebAuthnChallenge,
+) (*domain.WebAuthnChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge.ID = bson.NewObjectID()
+	challenge.CreatedAt = time.Now()
+
+	stored := *challenge
+	r.challenges = append(r.challenges, &stored)
+
+	return challenge, nil
+}
+
+func (r *fakeWebAuthnChallengeRepository) ConsumeChallenge(
+	_ context.Context,
+	ceremony string,
+	tokenHash string,
+) (*domain.WebAuthnChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, challenge := range r.challenges {
+		if challenge.Ceremony == ceremony && challenge.TokenHash == tokenHash &&
+			time.Now().Before(challenge.ExpiresAt) {
+			r.challenges = slices.Delete(r.challenges, i, i+1)
+			return challenge, nil
+		}
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+type fakeRevokedSessionRepository struct {
+	mu              sync.Mutex
+	revokedSessions []domain.RevokedSession
+}
+
+func (r *fakeRevokedSessionRepository) CreateRevokedSessions(
+	_ context.Context,
+	revokedSessions []*domain.RevokedSession,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, revokedSession := range revokedSessions {
+		r.revokedSessions = append(r.revokedSessions, *revokedSession)
+	}
+
+	return nil
+}
+
+func (r *fakeRevokedSessionRepository) ListRevokedSessions(
+	_ context.Context,
+	since time.Time,
+) ([]domain.RevokedSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var revokedSessions []domain.RevokedSession
+	for _, revokedSession := range r.revokedSessions {
+		if !revokedSession.RevokedAt.Before(since) {
+			revokedSessions = append(revokedSessions, revokedSession)
+		}
+	}
+
+	return revokedSessions, nil
+}
@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	"github.com/vasapolrittideah/optimize-api/shared/oauth"
+)
+
+var (
+	ErrIdentityNotFound      = errors.New("identity not found")
+	ErrIdentityAlreadyLinked = errors.New("identity is already linked to another user")
+	ErrIdentityNotUnlinkable = errors.New("email identity cannot be unlinked")
+	ErrLastLoginMethod       = errors.New("cannot remove the last login method")
+)
+
+func (u *authUsecase) ListIdentities(
+	ctx context.Context,
+	params domain.ListIdentitiesParams,
+) ([]domain.Identity, error) {
+	session, err := u.authenticateSession(ctx, params.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.identityRepo.GetIdentitiesByUserID(ctx, session.UserID)
+}
+
+func (u *authUsecase) LinkIdentity(
+	ctx context.Context,
+	params domain.LinkIdentityParams,
+) (*domain.OAuthAuthorization, error) {
+	session, err := u.authenticateSession(ctx, params.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.startOAuth(ctx, params.Provider, session.UserID)
+}
+
+func (u *authUsecase) UnlinkIdentity(ctx context.Context, params domain.UnlinkIdentityParams) error {
+	user, err := u.authenticateUser(ctx, params.AccessToken)
+	if err != nil {
+		return err
+	}
+
+	identities, err := u.identityRepo.GetIdentitiesByUserID(ctx, user.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	credentials, err := u.webAuthnCredentialRepo.GetCredentialsByUserID(ctx, user.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	// The password and every passkey count as login methods on their own, so the email
+	// identity stands for the password and cannot be unlinked. Magic links only prove
+	// access to the email address and leave the account to whoever controls the mailbox,
+	// so they do not count, while every OAuth identity is a login method of its own.
+	var target *domain.Identity
+	loginMethods := len(credentials)
+	if user.PasswordHash != "" {
+		loginMethods++
+	}
+	for i, identity := range identities {
+		if identity.ID.Hex() == params.IdentityID {
+			target = &identities[i]
+		}
+		if identity.Provider != domain.IdentityProviderEmail && identity.Provider != domain.IdentityProviderMagicLink {
+			loginMethods++
+		}
+	}
+
+	if target == nil {
+		return ErrIdentityNotFound
+	}
+
+	if target.Provider == domain.IdentityProviderEmail {
+		return ErrIdentityNotUnlinkable
+	}
+
+	if loginMethods <= 1 {
+		return ErrLastLoginMethod
+	}
+
+	if err := u.identityRepo.DeleteIdentity(ctx, params.IdentityID, user.ID.Hex()); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrIdentityNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// linkOAuthIdentity links the provider identity to the user, unless it already belongs to another user.
+func (u *authUsecase) linkOAuthIdentity(
+	ctx context.Context,
+	userID string,
+	provider string,
+	userInfo *oauth.UserInfo,
+) error {
+	identity, err := u.identityRepo.GetIdentityByProvider(ctx, userInfo.Subject, provider)
+	switch {
+	case err == nil:
+		if identity.UserID != userID {
+			return ErrIdentityAlreadyLinked
+		}
+
+		return nil
+	case !errors.Is(err, mongo.ErrNoDocuments):
+		return err
+	}
+
+	if _, err := u.identityRepo.CreateIdentity(ctx, &domain.Identity{
+		UserID:     userID,
+		Provider:   provider,
+		ProviderID: userInfo.Subject,
+		Email:      userInfo.Email,
+	}); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrIdentityAlreadyLinked
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// emailIdentityID returns the ID of the email and password identity of the user, or an empty
+// string if the user has none.
+func (u *authUsecase) emailIdentityID(ctx context.Context, userID string) (string, error) {
+	identities, err := u.identityRepo.GetIdentitiesByUserID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, identity := range identities {
+		if identity.Provider == domain.IdentityProviderEmail {
+			return identity.ID.Hex(), nil
+		}
+	}
+
+	return "", nil
+}
+
+// recordLastLogin records a sign-in with the identity. It is only called once the session has
+// been issued, so a failure is logged rather than returned to a user who is already signed in.
+func (u *authUsecase) recordLastLogin(ctx context.Context, identityID string) {
+	if identityID == "" {
+		return
+	}
+
+	if err := u.identityRepo.UpdateLastLogin(ctx, identityID); err != nil {
+		u.logger.Error().Err(err).Str("identity_id", identityID).Msg("failed to record last login")
+	}
+}
@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
+)
+
+func (u *authUsecase) IntrospectToken(
+	ctx context.Context,
+	params domain.IntrospectTokenParams,
+) (*domain.TokenIntrospection, error) {
+	// A token that cannot be used is reported as inactive rather than as an error, so that
+	// callers only need to check a single flag.
+	claims, err := u.parseToken(params.Token, u.accessTokenKeys, auth.TokenTypeAccess)
+	if err != nil {
+		return &domain.TokenIntrospection{Active: false}, nil
+	}
+
+	if _, err := u.liveSession(ctx, claims); err != nil {
+		if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrSessionRevoked) {
+			return &domain.TokenIntrospection{Active: false}, nil
+		}
+
+		return nil, err
+	}
+
+	introspection := &domain.TokenIntrospection{
+		Active:    true,
+		UserID:    claims.UserID,
+		SessionID: claims.SessionID,
+		Scopes:    claims.Scopes(),
+	}
+	if claims.ExpiresAt != nil {
+		introspection.ExpiresAt = claims.ExpiresAt.Time
+	}
+	if claims.IssuedAt != nil {
+		introspection.IssuedAt = claims.IssuedAt.Time
+	}
+
+	return introspection, nil
+}
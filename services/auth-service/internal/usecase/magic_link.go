@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
+)
+
+const magicLinkTokenIDSize = 32
+
+var ErrInvalidMagicLink = errors.New("invalid or expired magic link")
+
+// magicLinkClaims are the claims of the signed token embedded in a magic link. The token ID
+// is also stored as a one-time token so that the link can only be used once.
+type magicLinkClaims struct {
+	jwt.RegisteredClaims
+
+	CreateUser bool `json:"create_user,omitempty"`
+}
+
+func (u *authUsecase) RequestMagicLink(ctx context.Context, params domain.RequestMagicLinkParams) error {
+	userID := ""
+	user, err := u.userRepo.GetUserByEmail(ctx, params.Email)
+	switch {
+	case err == nil:
+		userID = user.ID.Hex()
+	case errors.Is(err, mongo.ErrNoDocuments):
+		// Unknown addresses are not reported to avoid leaking which emails are registered.
+		if !params.CreateUser {
+			return nil
+		}
+	default:
+		return err
+	}
+
+	// Only the most recently requested link of a registered user stays valid.
+	if userID != "" {
+		if err := u.oneTimeTokenRepo.DeleteTokensByUserID(ctx, userID, domain.TokenPurposeMagicLink); err != nil {
+			return err
+		}
+	}
+
+	tokenID, err := security.GenerateRandomToken(magicLinkTokenIDSize)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(u.authServiceCfg.MagicLink.ExpiresIn)
+	token, err := u.authenticator.GenerateToken(magicLinkClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Subject:   params.Email,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    u.authServiceCfg.Token.Issuer,
+			Audience:  jwt.ClaimStrings{u.authServiceCfg.Token.Issuer},
+		},
+		CreateUser: params.CreateUser && userID == "",
+	}, auth.NewSecretKeySet(u.authServiceCfg.MagicLink.Secret))
+	if err != nil {
+		return err
+	}
+
+	if _, err := u.oneTimeTokenRepo.CreateToken(ctx, &domain.OneTimeToken{
+		UserID:    userID,
+		Purpose:   domain.TokenPurposeMagicLink,
+		TokenHash: security.HashToken(tokenID),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	magicLinkURL, err := withToken(u.authServiceCfg.MagicLink.URL, token)
+	if err != nil {
+		return err
+	}
+
+	// A failure to send is only logged, since unknown addresses that are not signed up
+	// never wait for the mail server and never fail.
+	u.sendInBackground(
+		ctx,
+		userID,
+		newMagicLinkEmail(params.Email, magicLinkURL, u.authServiceCfg.MagicLink.ExpiresIn),
+	)
+
+	return nil
+}
+
+func (u *authUsecase) ConsumeMagicLink(
+	ctx context.Context,
+	params domain.ConsumeMagicLinkParams,
+) (*domain.SignInResult, error) {
+	email, tokenID, createUser, err := u.parseMagicLinkToken(params.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := u.oneTimeTokenRepo.ConsumeToken(ctx, domain.TokenPurposeMagicLink, security.HashToken(tokenID))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidMagicLink
+		}
+
+		return nil, err
+	}
+
+	user, err := u.userRepo.GetUserByEmail(ctx, email)
+	switch {
+	case err == nil:
+		// A link requested for one user must not sign in another user who has since
+		// registered with the same address.
+		if token.UserID != "" && token.UserID != user.ID.Hex() {
+			return nil, ErrInvalidMagicLink
+		}
+	case errors.Is(err, mongo.ErrNoDocuments):
+		if !createUser {
+			return nil, ErrInvalidMagicLink
+		}
+
+		user, err = u.userRepo.CreateUser(ctx, &domain.User{
+			Email:    email,
+			Verified: true,
+		})
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, ErrUserAlreadyExists
+			}
+
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	// Following the link proves ownership of the address.
+	if !user.Verified {
+		verified := true
+		if user, err = u.userRepo.UpdateUser(ctx, user.ID.Hex(), domain.UpdateUserParams{
+			Verified: &verified,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	identity, err := u.findOrCreateMagicLinkIdentity(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	mfaRequired, err := u.requiresMFA(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfaRequired {
+		mfaToken, err := u.generateMFAToken(ctx, user.ID.Hex(), identity.ID.Hex())
+		if err != nil {
+			return nil, err
+		}
+
+		return &domain.SignInResult{MFAToken: mfaToken}, nil
+	}
+
+	tokens, err := u.createAuthSession(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	u.recordLastLogin(ctx, identity.ID.Hex())
+
+	return &domain.SignInResult{Tokens: tokens}, nil
+}
+
+// parseMagicLinkToken validates the signed token of a magic link and returns the email address
+// it was sent to, its token ID and whether an unknown user may be created with it.
+func (u *authUsecase) parseMagicLinkToken(token string) (string, string, bool, error) {
+	parsed, err := u.authenticator.ValidateToken(token, auth.NewSecretKeySet(u.authServiceCfg.MagicLink.Secret))
+	if err != nil {
+		return "", "", false, fmt.Errorf("%w: %w", ErrInvalidMagicLink, err)
+	}
+
+	mapClaims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", "", false, ErrInvalidMagicLink
+	}
+
+	email, _ := mapClaims["sub"].(string)
+	tokenID, _ := mapClaims["jti"].(string)
+	createUser, _ := mapClaims["create_user"].(bool)
+	if email == "" || tokenID == "" {
+		return "", "", false, ErrInvalidMagicLink
+	}
+
+	return email, tokenID, createUser, nil
+}
+
+// findOrCreateMagicLinkIdentity returns the magic link identity of the user, creating it the
+// first time the user signs in with a magic link.
+func (u *authUsecase) findOrCreateMagicLinkIdentity(ctx context.Context, user *domain.User) (*domain.Identity, error) {
+	identity, err := u.identityRepo.GetIdentityByProvider(ctx, user.Email, domain.IdentityProviderMagicLink)
+	if err == nil {
+		if identity.UserID != user.ID.Hex() {
+			return nil, ErrIdentityAlreadyLinked
+		}
+
+		return identity, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	return u.identityRepo.CreateIdentity(ctx, &domain.Identity{
+		UserID:     user.ID.Hex(),
+		Provider:   domain.IdentityProviderMagicLink,
+		ProviderID: user.Email,
+		Email:      user.Email,
+	})
+}
@@ -0,0 +1,310 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	authtypes "github.com/vasapolrittideah/optimize-api/services/auth-service/pkg/types"
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
+)
+
+const mfaTokenIDSize = 32
+
+// mfaTokenClaims are the claims of the MFA challenge token. IdentityID is the identity the user
+// signed in with, whose last login is recorded once the second factor is verified.
+type mfaTokenClaims struct {
+	jwt.RegisteredClaims
+
+	IdentityID string `json:"identity_id,omitempty"`
+}
+
+var (
+	ErrMFAAlreadyEnabled  = errors.New("multi-factor authentication already enabled")
+	ErrMFANotEnrolled     = errors.New("multi-factor authentication not enrolled")
+	ErrInvalidMFACode     = errors.New("invalid multi-factor authentication code")
+	ErrInvalidMFAToken    = errors.New("invalid multi-factor authentication token")
+	ErrTooManyMFAAttempts = errors.New("too many multi-factor authentication attempts")
+)
+
+func (u *authUsecase) EnrollTOTP(ctx context.Context, params domain.EnrollTOTPParams) (*domain.TOTPEnrollment, error) {
+	user, err := u.authenticateUser(ctx, params.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedSecret, err := u.encryptor.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	// The secret stays pending until the user proves their authenticator app produces valid codes.
+	if _, err := u.userRepo.UpdateUser(ctx, user.ID.Hex(), domain.UpdateUserParams{
+		TOTPSecret: &encryptedSecret,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &domain.TOTPEnrollment{
+		Secret: secret,
+		URI:    security.TOTPURI(u.totpIssuer(), user.Email, secret),
+	}, nil
+}
+
+func (u *authUsecase) ConfirmTOTP(ctx context.Context, params domain.ConfirmTOTPParams) error {
+	user, err := u.authenticateUser(ctx, params.AccessToken)
+	if err != nil {
+		return err
+	}
+
+	if user.TOTPEnabled {
+		return ErrMFAAlreadyEnabled
+	}
+
+	if user.TOTPSecret == "" {
+		return ErrMFANotEnrolled
+	}
+
+	if err := u.verifyTOTPCode(ctx, user, params.Code); err != nil {
+		return err
+	}
+
+	enabled := true
+	if _, err := u.userRepo.UpdateUser(ctx, user.ID.Hex(), domain.UpdateUserParams{
+		TOTPEnabled: &enabled,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *authUsecase) DisableTOTP(ctx context.Context, params domain.DisableTOTPParams) error {
+	user, err := u.authenticateUser(ctx, params.AccessToken)
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPEnabled {
+		return ErrMFANotEnrolled
+	}
+
+	if err := u.verifyTOTPCode(ctx, user, params.Code); err != nil {
+		return err
+	}
+
+	// The recovery codes are removed too, so that they do not become valid again if the user
+	// enrolls another authenticator later.
+	disabled := false
+	emptySecret := ""
+	noRecoveryCodes := []string{}
+	if _, err := u.userRepo.UpdateUser(ctx, user.ID.Hex(), domain.UpdateUserParams{
+		TOTPSecret:    &emptySecret,
+		TOTPEnabled:   &disabled,
+		RecoveryCodes: &noRecoveryCodes,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *authUsecase) VerifyMFA(ctx context.Context, params domain.VerifyMFAParams) (*authtypes.Tokens, error) {
+	userID, tokenID, identityID, err := u.parseMFAToken(params.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Attempts are counted before the code is checked so that concurrent guesses
+	// cannot exceed the limit. Once it is reached, the user has to sign in again.
+	challenge, err := u.oneTimeTokenRepo.IncrementTokenAttempts(
+		ctx,
+		domain.TokenPurposeMFAChallenge,
+		security.HashToken(tokenID),
+	)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidMFAToken
+		}
+
+		return nil, err
+	}
+
+	if challenge.Attempts > u.authServiceCfg.MFA.MaxAttempts {
+		return nil, ErrTooManyMFAAttempts
+	}
+
+	user, err := u.userRepo.GetUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidMFAToken
+		}
+
+		return nil, err
+	}
+
+	if !user.TOTPEnabled {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if err := u.verifyTOTPCode(ctx, user, params.Code); err != nil {
+		return nil, err
+	}
+
+	// The token is consumed so that it cannot be exchanged for another session.
+	if _, err := u.oneTimeTokenRepo.ConsumeToken(
+		ctx,
+		domain.TokenPurposeMFAChallenge,
+		security.HashToken(tokenID),
+	); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidMFAToken
+		}
+
+		return nil, err
+	}
+
+	tokens, err := u.createAuthSession(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	u.recordLastLogin(ctx, identityID)
+
+	return tokens, nil
+}
+
+// authenticateUser validates the access token and returns the user it was issued to.
+func (u *authUsecase) authenticateUser(ctx context.Context, accessToken string) (*domain.User, error) {
+	session, err := u.authenticateSession(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.userRepo.GetUser(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidToken
+		}
+
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// verifyTOTPCode checks the code against the user's TOTP secret and records its time step
+// so that the same code cannot be replayed.
+func (u *authUsecase) verifyTOTPCode(ctx context.Context, user *domain.User, code string) error {
+	secret, err := u.encryptor.Decrypt(user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+
+	step, ok, err := security.ValidateTOTPCode(secret, code, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if !ok || step <= user.TOTPLastUsedStep {
+		return ErrInvalidMFACode
+	}
+
+	if err := u.userRepo.UseTOTPStep(ctx, user.ID.Hex(), step); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrInvalidMFACode
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// generateMFAToken generates the short-lived challenge token returned by SignIn for users with MFA enabled.
+// The token ID is also stored as a one-time token, which counts the codes tried with the token.
+// identityID is the identity the user signed in with, or empty if there is none.
+func (u *authUsecase) generateMFAToken(ctx context.Context, userID, identityID string) (string, error) {
+	tokenID, err := security.GenerateRandomToken(mfaTokenIDSize)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(u.authServiceCfg.MFA.ChallengeExpiresIn)
+	claims := mfaTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    u.authServiceCfg.Token.Issuer,
+			Audience:  jwt.ClaimStrings{u.authServiceCfg.Token.Issuer},
+		},
+		IdentityID: identityID,
+	}
+
+	token, err := u.authenticator.GenerateToken(claims, auth.NewSecretKeySet(u.authServiceCfg.MFA.ChallengeSecret))
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := u.oneTimeTokenRepo.CreateToken(ctx, &domain.OneTimeToken{
+		UserID:    userID,
+		Purpose:   domain.TokenPurposeMFAChallenge,
+		TokenHash: security.HashToken(tokenID),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// parseMFAToken validates the MFA challenge token and returns the ID of the user it was issued to,
+// its token ID and the ID of the identity the user signed in with.
+func (u *authUsecase) parseMFAToken(token string) (string, string, string, error) {
+	parsed, err := u.authenticator.ValidateToken(token, auth.NewSecretKeySet(u.authServiceCfg.MFA.ChallengeSecret))
+	if err != nil {
+		return "", "", "", fmt.Errorf("%w: %w", ErrInvalidMFAToken, err)
+	}
+
+	mapClaims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", "", "", ErrInvalidMFAToken
+	}
+
+	userID, _ := mapClaims["sub"].(string)
+	tokenID, _ := mapClaims["jti"].(string)
+	identityID, _ := mapClaims["identity_id"].(string)
+	if userID == "" || tokenID == "" {
+		return "", "", "", ErrInvalidMFAToken
+	}
+
+	return userID, tokenID, identityID, nil
+}
+
+// totpIssuer returns the issuer name shown in authenticator apps.
+func (u *authUsecase) totpIssuer() string {
+	if u.authServiceCfg.MFA.TOTPIssuer != "" {
+		return u.authServiceCfg.MFA.TOTPIssuer
+	}
+
+	return u.authServiceCfg.Token.Issuer
+}
@@ -0,0 +1,223 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"golang.org/x/oauth2"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	"github.com/vasapolrittideah/optimize-api/shared/oauth"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
+)
+
+const oauthStateSize = 32
+
+var (
+	ErrUnknownOAuthProvider = errors.New("unknown OAuth provider")
+	ErrInvalidOAuthState    = errors.New("invalid or expired OAuth state")
+	ErrOAuthFailed          = errors.New("failed to sign in with OAuth provider")
+	ErrOAuthEmailRequired   = errors.New("OAuth provider did not return an email address")
+)
+
+func (u *authUsecase) BeginOAuth(
+	ctx context.Context,
+	params domain.BeginOAuthParams,
+) (*domain.OAuthAuthorization, error) {
+	return u.startOAuth(ctx, params.Provider, "")
+}
+
+func (u *authUsecase) CompleteOAuth(
+	ctx context.Context,
+	params domain.CompleteOAuthParams,
+) (*domain.OAuthCompletion, error) {
+	provider, err := u.oauthProvider(params.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := u.oauthStateRepo.ConsumeState(ctx, provider.Name(), security.HashToken(params.State))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidOAuthState
+		}
+
+		return nil, err
+	}
+
+	userInfo, err := provider.Exchange(ctx, params.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOAuthFailed, err)
+	}
+
+	if state.UserID != "" {
+		if err := u.linkOAuthIdentity(ctx, state.UserID, provider.Name(), userInfo); err != nil {
+			return nil, err
+		}
+
+		return &domain.OAuthCompletion{IdentityLinked: true}, nil
+	}
+
+	identity, err := u.findOrCreateOAuthIdentity(ctx, provider.Name(), userInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.userRepo.GetUser(ctx, identity.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.authServiceCfg.Verification.RequireVerifiedEmail && !user.Verified {
+		return nil, ErrEmailNotVerified
+	}
+
+	mfaRequired, err := u.requiresMFA(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfaRequired {
+		mfaToken, err := u.generateMFAToken(ctx, user.ID.Hex(), identity.ID.Hex())
+		if err != nil {
+			return nil, err
+		}
+
+		return &domain.OAuthCompletion{SignInResult: domain.SignInResult{MFAToken: mfaToken}}, nil
+	}
+
+	tokens, err := u.createAuthSession(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	u.recordLastLogin(ctx, identity.ID.Hex())
+
+	return &domain.OAuthCompletion{SignInResult: domain.SignInResult{Tokens: tokens}}, nil
+}
+
+// startOAuth creates the state of a new OAuth flow and returns the authorization URL of the
+// provider. The flow signs a user in, unless userID is set, in which case the provider
+// identity is linked to that user.
+func (u *authUsecase) startOAuth(
+	ctx context.Context,
+	providerName string,
+	userID string,
+) (*domain.OAuthAuthorization, error) {
+	provider, err := u.oauthProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := security.GenerateRandomToken(oauthStateSize)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := security.GenerateRandomToken(oauthStateSize)
+	if err != nil {
+		return nil, err
+	}
+
+	codeVerifier := oauth2.GenerateVerifier()
+
+	if _, err := u.oauthStateRepo.CreateState(ctx, &domain.OAuthState{
+		StateHash:    security.HashToken(state),
+		Provider:     provider.Name(),
+		UserID:       userID,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(u.authServiceCfg.OAuth.StateExpiresIn),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &domain.OAuthAuthorization{
+		URL:   provider.AuthCodeURL(state, nonce, oauth2.S256ChallengeFromVerifier(codeVerifier)),
+		State: state,
+	}, nil
+}
+
+// oauthProvider returns the configured OAuth provider with the given name.
+func (u *authUsecase) oauthProvider(name string) (oauth.Provider, error) {
+	provider, err := u.oauthProviders.Provider(name)
+	if err != nil {
+		if errors.Is(err, oauth.ErrUnknownProvider) {
+			return nil, ErrUnknownOAuthProvider
+		}
+
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+// findOrCreateOAuthIdentity returns the provider identity of the user. An unknown identity is
+// linked to the user with the same email address, or to a new user when there is none.
+func (u *authUsecase) findOrCreateOAuthIdentity(
+	ctx context.Context,
+	provider string,
+	userInfo *oauth.UserInfo,
+) (*domain.Identity, error) {
+	identity, err := u.identityRepo.GetIdentityByProvider(ctx, userInfo.Subject, provider)
+	if err == nil {
+		return identity, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	if userInfo.Email == "" {
+		return nil, ErrOAuthEmailRequired
+	}
+
+	created := false
+	user, err := u.userRepo.GetUserByEmail(ctx, userInfo.Email)
+	switch {
+	case err == nil:
+		// Linking requires both sides to have proven ownership of the address, otherwise
+		// whoever registered it first could take over the account of the other.
+		if !userInfo.EmailVerified || !user.Verified {
+			return nil, ErrUserAlreadyExists
+		}
+	case errors.Is(err, mongo.ErrNoDocuments):
+		user, err = u.userRepo.CreateUser(ctx, &domain.User{
+			Email:    userInfo.Email,
+			FullName: userInfo.Name,
+			Verified: userInfo.EmailVerified,
+		})
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, ErrUserAlreadyExists
+			}
+
+			return nil, err
+		}
+		created = true
+	default:
+		return nil, err
+	}
+
+	identity, err = u.identityRepo.CreateIdentity(ctx, &domain.Identity{
+		UserID:     user.ID.Hex(),
+		Provider:   provider,
+		ProviderID: userInfo.Subject,
+		Email:      userInfo.Email,
+	})
+	if err != nil {
+		// A user created without its identity could never sign in with the provider, and
+		// its email would make every later attempt fail, so it is removed again.
+		if created {
+			if _, deleteErr := u.userRepo.DeleteUser(ctx, user.ID.Hex()); deleteErr != nil {
+				u.logger.Error().Err(deleteErr).Str("user_id", user.ID.Hex()).Msg("failed to delete user")
+			}
+		}
+
+		return nil, err
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,479 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	"github.com/vasapolrittideah/optimize-api/shared/oauth"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
+)
+
+const (
+	fakeOIDCProviderName = "fake"
+	fakeOIDCClientID     = "auth-service"
+	fakeOIDCKeyID        = "fake-key"
+)
+
+// fakeOIDCIdentity is the identity the fake provider asserts for an authorization code.
+type fakeOIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type fakeOIDCAuthorization struct {
+	identity      fakeOIDCIdentity
+	nonce         string
+	codeChallenge string
+}
+
+// fakeOIDCServer is a local OpenID Connect provider. It skips the interactive part of the
+// authorization code flow, issuing codes for whatever identity the test asks for, but
+// performs discovery, PKCE verification and ID token signing like a real provider.
+type fakeOIDCServer struct {
+	*httptest.Server
+
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]fakeOIDCAuthorization
+
+	// nonce, when set, replaces the nonce of the authorization in the ID tokens issued.
+	nonce string
+}
+
+func newFakeOIDCServer(t *testing.T) *fakeOIDCServer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate provider key: %v", err)
+	}
+
+	s := &fakeOIDCServer{
+		key:   key,
+		codes: make(map[string]fakeOIDCAuthorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	mux.HandleFunc("POST /token", s.token)
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// provider returns an OIDC provider configured against the fake server.
+func (s *fakeOIDCServer) provider(t *testing.T) oauth.Provider {
+	t.Helper()
+
+	provider, err := oauth.NewOIDCProvider(t.Context(), fakeOIDCProviderName, oauth.OIDCConfig{
+		IssuerURL:   s.URL,
+		ClientID:    fakeOIDCClientID,
+		RedirectURL: "https://app.example.com/oauth/callback",
+		Scopes:      []string{"openid", "email", "profile"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create OIDC provider: %v", err)
+	}
+
+	return provider
+}
+
+// authorize plays the part of the user signing in at the provider. It returns the
+// authorization code and state the provider redirects back with.
+func (s *fakeOIDCServer) authorize(t *testing.T, authURL string, identity fakeOIDCIdentity) (string, string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("failed to parse authorization URL: %v", err)
+	}
+
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorization URL does not use PKCE with S256: %s", authURL)
+	}
+
+	code := rand.Text()
+
+	s.mu.Lock()
+	s.codes[code] = fakeOIDCAuthorization{
+		identity:      identity,
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+	}
+	s.mu.Unlock()
+
+	return code, query.Get("state")
+}
+
+func (s *fakeOIDCServer) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]any{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (s *fakeOIDCServer) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": fakeOIDCKeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *fakeOIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	authorization, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	nonce := s.nonce
+	s.mu.Unlock()
+
+	if !ok {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	verifierDigest := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(verifierDigest[:]) != authorization.codeChallenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	if nonce == "" {
+		nonce = authorization.nonce
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            authorization.identity.Subject,
+		"aud":            fakeOIDCClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          nonce,
+		"email":          authorization.identity.Email,
+		"email_verified": authorization.identity.EmailVerified,
+		"name":           authorization.identity.Name,
+	})
+	idToken.Header["kid"] = fakeOIDCKeyID
+
+	signed, err := idToken.SignedString(s.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]any{
+		"access_token": "provider-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// signInWithOAuth runs the whole OAuth sign-in flow against the fake provider.
+func signInWithOAuth(
+	t *testing.T,
+	u *testUsecase,
+	server *fakeOIDCServer,
+	identity fakeOIDCIdentity,
+) (*domain.OAuthCompletion, error) {
+	t.Helper()
+
+	authorization, err := u.BeginOAuth(t.Context(), domain.BeginOAuthParams{Provider: fakeOIDCProviderName})
+	if err != nil {
+		t.Fatalf("failed to begin OAuth: %v", err)
+	}
+
+	code, state := server.authorize(t, authorization.URL, identity)
+	if state != authorization.State {
+		t.Fatalf("provider redirected back with state %q, want %q", state, authorization.State)
+	}
+
+	return u.CompleteOAuth(t.Context(), domain.CompleteOAuthParams{
+		Provider: fakeOIDCProviderName,
+		State:    state,
+		Code:     code,
+	})
+}
+
+func TestCompleteOAuth_CreatesUserOnFirstSignIn(t *testing.T) {
+	server := newFakeOIDCServer(t)
+	u := newTestUsecase(t, nil, server.provider(t))
+
+	identity := fakeOIDCIdentity{
+		Subject:       "subject-1",
+		Email:         "new@example.com",
+		EmailVerified: true,
+		Name:          "New User",
+	}
+
+	completion, err := signInWithOAuth(t, u, server, identity)
+	if err != nil {
+		t.Fatalf("CompleteOAuth() error = %v", err)
+	}
+	if completion.Tokens == nil {
+		t.Fatal("CompleteOAuth() returned no tokens")
+	}
+
+	user, err := u.users.GetUserByEmail(t.Context(), identity.Email)
+	if err != nil {
+		t.Fatalf("user was not created: %v", err)
+	}
+	if !user.Verified || user.FullName != identity.Name {
+		t.Errorf("created user = %+v, want verified user named %q", user, identity.Name)
+	}
+
+	if claims := u.accessTokenClaims(t, completion.Tokens.AccessToken); claims.UserID != user.ID.Hex() {
+		t.Errorf("access token issued to %q, want %q", claims.UserID, user.ID.Hex())
+	}
+
+	// Signing in again finds the identity rather than creating another user.
+	if _, err := signInWithOAuth(t, u, server, identity); err != nil {
+		t.Fatalf("second CompleteOAuth() error = %v", err)
+	}
+
+	users, _ := u.users.ListUsers(t.Context(), domain.FilterUsersParams{})
+	if len(users) != 1 {
+		t.Errorf("got %d users after signing in twice, want 1", len(users))
+	}
+}
+
+func TestCompleteOAuth_LinksVerifiedEmailToExistingUser(t *testing.T) {
+	server := newFakeOIDCServer(t)
+	u := newTestUsecase(t, nil, server.provider(t))
+
+	user := u.createUser(t, "existing@example.com")
+
+	completion, err := signInWithOAuth(t, u, server, fakeOIDCIdentity{
+		Subject:       "subject-1",
+		Email:         user.Email,
+		EmailVerified: true,
+	})
+	if err != nil {
+		t.Fatalf("CompleteOAuth() error = %v", err)
+	}
+
+	if claims := u.accessTokenClaims(t, completion.Tokens.AccessToken); claims.UserID != user.ID.Hex() {
+		t.Errorf("access token issued to %q, want existing user %q", claims.UserID, user.ID.Hex())
+	}
+}
+
+func TestCompleteOAuth_RejectsUnverifiedEmailOfExistingUser(t *testing.T) {
+	server := newFakeOIDCServer(t)
+	u := newTestUsecase(t, nil, server.provider(t))
+
+	user := u.createUser(t, "existing@example.com")
+
+	_, err := signInWithOAuth(t, u, server, fakeOIDCIdentity{
+		Subject: "subject-1",
+		Email:   user.Email,
+	})
+	if !errors.Is(err, ErrUserAlreadyExists) {
+		t.Fatalf("CompleteOAuth() error = %v, want %v", err, ErrUserAlreadyExists)
+	}
+
+	if identities, _ := u.identities.GetIdentitiesByUserID(t.Context(), user.ID.Hex()); len(identities) != 0 {
+		t.Errorf("identity was linked to the existing user: %+v", identities)
+	}
+}
+
+func TestCompleteOAuth_RemovesUserWhenIdentityCannotBeCreated(t *testing.T) {
+	server := newFakeOIDCServer(t)
+	u := newTestUsecase(t, nil, server.provider(t))
+
+	identity := fakeOIDCIdentity{
+		Subject:       "subject-1",
+		Email:         "new@example.com",
+		EmailVerified: true,
+	}
+
+	createErr := errors.New("identity store unavailable")
+	u.identities.createErr = createErr
+
+	if _, err := signInWithOAuth(t, u, server, identity); !errors.Is(err, createErr) {
+		t.Fatalf("CompleteOAuth() error = %v, want %v", err, createErr)
+	}
+
+	if _, err := u.users.GetUserByEmail(t.Context(), identity.Email); err == nil {
+		t.Fatal("user without an identity was left behind")
+	}
+
+	// Once the identity can be stored, the next attempt signs the user in.
+	u.identities.createErr = nil
+
+	completion, err := signInWithOAuth(t, u, server, identity)
+	if err != nil {
+		t.Fatalf("CompleteOAuth() after recovery error = %v", err)
+	}
+	if completion.Tokens == nil {
+		t.Fatal("CompleteOAuth() after recovery returned no tokens")
+	}
+}
+
+func TestCompleteOAuth_RejectsReusedState(t *testing.T) {
+	server := newFakeOIDCServer(t)
+	u := newTestUsecase(t, nil, server.provider(t))
+
+	authorization, err := u.BeginOAuth(t.Context(), domain.BeginOAuthParams{Provider: fakeOIDCProviderName})
+	if err != nil {
+		t.Fatalf("BeginOAuth() error = %v", err)
+	}
+
+	identity := fakeOIDCIdentity{Subject: "subject-1", Email: "new@example.com", EmailVerified: true}
+	code, state := server.authorize(t, authorization.URL, identity)
+
+	params := domain.CompleteOAuthParams{Provider: fakeOIDCProviderName, State: state, Code: code}
+	if _, err := u.CompleteOAuth(t.Context(), params); err != nil {
+		t.Fatalf("CompleteOAuth() error = %v", err)
+	}
+
+	if _, err := u.CompleteOAuth(t.Context(), params); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Fatalf("CompleteOAuth() with a used state error = %v, want %v", err, ErrInvalidOAuthState)
+	}
+}
+
+func TestCompleteOAuth_RejectsNonceMismatch(t *testing.T) {
+	server := newFakeOIDCServer(t)
+	u := newTestUsecase(t, nil, server.provider(t))
+
+	server.nonce = "replayed-nonce"
+
+	_, err := signInWithOAuth(t, u, server, fakeOIDCIdentity{
+		Subject:       "subject-1",
+		Email:         "new@example.com",
+		EmailVerified: true,
+	})
+	if !errors.Is(err, ErrOAuthFailed) {
+		t.Fatalf("CompleteOAuth() error = %v, want %v", err, ErrOAuthFailed)
+	}
+
+	if _, err := u.users.GetUserByEmail(t.Context(), "new@example.com"); err == nil {
+		t.Error("user was created from an ID token with the wrong nonce")
+	}
+}
+
+func TestCompleteOAuth_RecordsLastLoginOnceMFAIsVerified(t *testing.T) {
+	server := newFakeOIDCServer(t)
+	u := newTestUsecase(t, nil, server.provider(t))
+
+	user := u.createUser(t, "existing@example.com")
+	secret := u.enableTOTP(t, user.ID.Hex())
+
+	oidcIdentity := fakeOIDCIdentity{Subject: "subject-1", Email: user.Email, EmailVerified: true}
+
+	completion, err := signInWithOAuth(t, u, server, oidcIdentity)
+	if err != nil {
+		t.Fatalf("CompleteOAuth() error = %v", err)
+	}
+	if completion.MFAToken == "" {
+		t.Fatal("CompleteOAuth() did not ask for the second factor")
+	}
+
+	identity, err := u.identities.GetIdentityByProvider(t.Context(), oidcIdentity.Subject, fakeOIDCProviderName)
+	if err != nil {
+		t.Fatalf("identity was not created: %v", err)
+	}
+	if !identity.LastLoginAt.IsZero() {
+		t.Fatal("last login was recorded before the second factor was verified")
+	}
+
+	code, err := security.GenerateTOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to generate TOTP code: %v", err)
+	}
+
+	if _, err := u.VerifyMFA(t.Context(), domain.VerifyMFAParams{
+		MFAToken: completion.MFAToken,
+		Code:     code,
+	}); err != nil {
+		t.Fatalf("VerifyMFA() error = %v", err)
+	}
+
+	if identity, _ = u.identities.GetIdentityByProvider(
+		t.Context(), oidcIdentity.Subject, fakeOIDCProviderName,
+	); identity.LastLoginAt.IsZero() {
+		t.Error("last login was not recorded once the second factor was verified")
+	}
+}
+
+func TestUnlinkIdentity_CountsPasskeysButNotMagicLinks(t *testing.T) {
+	u := newTestUsecase(t, nil)
+
+	user := u.createUser(t, "user@example.com")
+	accessToken := u.signIn(t, user.ID.Hex())
+
+	oauthIdentity, err := u.identities.CreateIdentity(t.Context(), &domain.Identity{
+		UserID:     user.ID.Hex(),
+		ProviderID: "subject-1",
+		Provider:   fakeOIDCProviderName,
+		Email:      user.Email,
+	})
+	if err != nil {
+		t.Fatalf("failed to create identity: %v", err)
+	}
+	if _, err := u.identities.CreateIdentity(t.Context(), &domain.Identity{
+		UserID:     user.ID.Hex(),
+		ProviderID: user.Email,
+		Provider:   domain.IdentityProviderMagicLink,
+		Email:      user.Email,
+	}); err != nil {
+		t.Fatalf("failed to create identity: %v", err)
+	}
+
+	params := domain.UnlinkIdentityParams{AccessToken: accessToken, IdentityID: oauthIdentity.ID.Hex()}
+
+	// A magic link alone would leave the account to whoever controls the mailbox.
+	if err := u.UnlinkIdentity(t.Context(), params); !errors.Is(err, ErrLastLoginMethod) {
+		t.Fatalf("UnlinkIdentity() with only a magic link left error = %v, want %v", err, ErrLastLoginMethod)
+	}
+
+	if _, err := u.passkeys.CreateCredential(t.Context(), &domain.WebAuthnCredential{
+		UserID:       user.ID.Hex(),
+		CredentialID: []byte("credential-id"),
+	}); err != nil {
+		t.Fatalf("failed to create passkey: %v", err)
+	}
+
+	if err := u.UnlinkIdentity(t.Context(), params); err != nil {
+		t.Fatalf("UnlinkIdentity() with a passkey left error = %v", err)
+	}
+}
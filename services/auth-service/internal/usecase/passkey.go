@@ -0,0 +1,457 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	authtypes "github.com/vasapolrittideah/optimize-api/services/auth-service/pkg/types"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
+)
+
+const passkeyChallengeIDSize = 32
+
+var (
+	ErrPasskeysDisabled         = errors.New("passkeys are not enabled")
+	ErrInvalidPasskeyChallenge  = errors.New("invalid or expired passkey challenge")
+	ErrInvalidPasskey           = errors.New("invalid passkey")
+	ErrPasskeyAlreadyRegistered = errors.New("passkey already registered")
+)
+
+func (u *authUsecase) BeginPasskeyRegistration(
+	ctx context.Context,
+	params domain.BeginPasskeyRegistrationParams,
+) (*domain.PasskeyChallenge, error) {
+	if u.webAuthn == nil {
+		return nil, ErrPasskeysDisabled
+	}
+
+	user, err := u.authenticateUser(ctx, params.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeyUser, err := u.newWebAuthnUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resident keys are preferred so that the passkey can also be used without entering an
+	// email address, while security keys without storage can still act as a second factor.
+	creation, session, err := u.webAuthn.BeginRegistration(
+		passkeyUser,
+		webauthn.WithExclusions(webauthn.Credentials(passkeyUser.WebAuthnCredentials()).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.createPasskeyChallenge(ctx, &domain.WebAuthnChallenge{
+		Ceremony: domain.WebAuthnCeremonyRegistration,
+		UserID:   user.ID.Hex(),
+	}, creation, session)
+}
+
+func (u *authUsecase) FinishPasskeyRegistration(
+	ctx context.Context,
+	params domain.FinishPasskeyRegistrationParams,
+) (*domain.WebAuthnCredential, error) {
+	if u.webAuthn == nil {
+		return nil, ErrPasskeysDisabled
+	}
+
+	user, err := u.authenticateUser(ctx, params.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, session, err := u.consumePasskeyChallenge(ctx, domain.WebAuthnCeremonyRegistration, params.ChallengeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if challenge.UserID != user.ID.Hex() {
+		return nil, ErrInvalidPasskeyChallenge
+	}
+
+	passkeyUser, err := u.newWebAuthnUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(params.Credential)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+	}
+
+	credential, err := u.webAuthn.CreateCredential(passkeyUser, *session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	created, err := u.webAuthnCredentialRepo.CreateCredential(ctx, &domain.WebAuthnCredential{
+		UserID:          user.ID.Hex(),
+		Name:            params.Name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrPasskeyAlreadyRegistered
+		}
+
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (u *authUsecase) BeginPasskeyLogin(
+	ctx context.Context,
+	params domain.BeginPasskeyLoginParams,
+) (*domain.PasskeyChallenge, error) {
+	if u.webAuthn == nil {
+		return nil, ErrPasskeysDisabled
+	}
+
+	// Without a password, the passkey is the only factor, so the authenticator must verify the user.
+	if params.MFAToken == "" {
+		assertion, session, err := u.webAuthn.BeginDiscoverableLogin(
+			webauthn.WithUserVerification(protocol.VerificationRequired),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return u.createPasskeyChallenge(ctx, &domain.WebAuthnChallenge{
+			Ceremony: domain.WebAuthnCeremonyLogin,
+		}, assertion, session)
+	}
+
+	userID, tokenID, identityID, err := u.parseMFAToken(params.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.userRepo.GetUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidMFAToken
+		}
+
+		return nil, err
+	}
+
+	passkeyUser, err := u.newWebAuthnUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(passkeyUser.WebAuthnCredentials()) == 0 {
+		return nil, ErrMFANotEnrolled
+	}
+
+	assertion, session, err := u.webAuthn.BeginLogin(passkeyUser)
+	if err != nil {
+		return nil, err
+	}
+
+	// The MFA token is only consumed once the passkey is presented, so that it cannot be
+	// exchanged for more than one session.
+	return u.createPasskeyChallenge(ctx, &domain.WebAuthnChallenge{
+		Ceremony:     domain.WebAuthnCeremonyLogin,
+		UserID:       userID,
+		IdentityID:   identityID,
+		MFATokenHash: security.HashToken(tokenID),
+	}, assertion, session)
+}
+
+func (u *authUsecase) FinishPasskeyLogin(
+	ctx context.Context,
+	params domain.FinishPasskeyLoginParams,
+) (*authtypes.Tokens, error) {
+	if u.webAuthn == nil {
+		return nil, ErrPasskeysDisabled
+	}
+
+	challenge, session, err := u.consumePasskeyChallenge(ctx, domain.WebAuthnCeremonyLogin, params.ChallengeID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(params.Credential)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+	}
+
+	var (
+		passkeyUser *webAuthnUser
+		credential  *webauthn.Credential
+	)
+	if challenge.UserID != "" {
+		user, err := u.userRepo.GetUser(ctx, challenge.UserID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, ErrInvalidPasskey
+			}
+
+			return nil, err
+		}
+
+		if passkeyUser, err = u.newWebAuthnUser(ctx, user); err != nil {
+			return nil, err
+		}
+
+		if credential, err = u.webAuthn.ValidateLogin(passkeyUser, *session, parsed); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+		}
+	} else {
+		// The user handle stored in the passkey is the ID of the user it was registered for.
+		handler := func(_, userHandle []byte) (webauthn.User, error) {
+			user, err := u.userRepo.GetUser(ctx, string(userHandle))
+			if err != nil {
+				return nil, err
+			}
+
+			return u.newWebAuthnUser(ctx, user)
+		}
+
+		user, validated, err := u.webAuthn.ValidatePasskeyLogin(handler, *session, parsed)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPasskey, err)
+		}
+
+		passkeyUser, _ = user.(*webAuthnUser)
+		credential = validated
+	}
+
+	stored := passkeyUser.credential(credential.ID)
+	if stored == nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	// A sign counter that did not increase means that the private key may have been copied
+	// to another authenticator, so the passkey is flagged and can no longer be used.
+	if credential.Authenticator.CloneWarning {
+		cloneWarning := true
+		if err := u.webAuthnCredentialRepo.UpdateCredential(ctx, stored.ID.Hex(), domain.UpdateWebAuthnCredentialParams{
+			CloneWarning: &cloneWarning,
+		}); err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w: sign counter did not increase", ErrInvalidPasskey)
+	}
+
+	now := time.Now()
+	if err := u.webAuthnCredentialRepo.UpdateCredential(ctx, stored.ID.Hex(), domain.UpdateWebAuthnCredentialParams{
+		SignCount:   &credential.Authenticator.SignCount,
+		BackupState: &credential.Flags.BackupState,
+		LastUsedAt:  &now,
+	}); err != nil {
+		return nil, err
+	}
+
+	if challenge.UserID == "" &&
+		u.authServiceCfg.Verification.RequireVerifiedEmail && !passkeyUser.user.Verified {
+		return nil, ErrEmailNotVerified
+	}
+
+	// The MFA token is consumed so that it cannot be exchanged for another session.
+	if challenge.MFATokenHash != "" {
+		if _, err := u.oneTimeTokenRepo.ConsumeToken(
+			ctx,
+			domain.TokenPurposeMFAChallenge,
+			challenge.MFATokenHash,
+		); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, ErrInvalidMFAToken
+			}
+
+			return nil, err
+		}
+	}
+
+	tokens, err := u.createAuthSession(ctx, passkeyUser.user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	// Passkeys have no identity of their own. When the passkey was the second factor, the sign-in
+	// is recorded for the identity used first.
+	u.recordLastLogin(ctx, challenge.IdentityID)
+
+	return tokens, nil
+}
+
+// requiresMFA reports whether the user has to complete a second factor after signing in,
+// either with a TOTP code or with one of their passkeys.
+func (u *authUsecase) requiresMFA(ctx context.Context, user *domain.User) (bool, error) {
+	if user.TOTPEnabled {
+		return true, nil
+	}
+
+	passkeyUser, err := u.newWebAuthnUser(ctx, user)
+	if err != nil {
+		return false, err
+	}
+
+	return len(passkeyUser.WebAuthnCredentials()) > 0, nil
+}
+
+// createPasskeyChallenge stores the challenge with the session data of a WebAuthn ceremony
+// and returns the options to pass to the browser together with the ID that identifies the
+// ceremony.
+func (u *authUsecase) createPasskeyChallenge(
+	ctx context.Context,
+	challenge *domain.WebAuthnChallenge,
+	options any,
+	session *webauthn.SessionData,
+) (*domain.PasskeyChallenge, error) {
+	encodedOptions, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionData, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+
+	challengeID, err := security.GenerateRandomToken(passkeyChallengeIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge.TokenHash = security.HashToken(challengeID)
+	challenge.SessionData = sessionData
+	challenge.ExpiresAt = time.Now().Add(u.authServiceCfg.WebAuthn.ChallengeExpiresIn)
+
+	if _, err := u.webAuthnChallengeRepo.CreateChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	return &domain.PasskeyChallenge{
+		ChallengeID: challengeID,
+		Options:     encodedOptions,
+	}, nil
+}
+
+// consumePasskeyChallenge returns the pending WebAuthn ceremony with the given ID and its
+// session data. A challenge can only be consumed once.
+func (u *authUsecase) consumePasskeyChallenge(
+	ctx context.Context,
+	ceremony string,
+	challengeID string,
+) (*domain.WebAuthnChallenge, *webauthn.SessionData, error) {
+	challenge, err := u.webAuthnChallengeRepo.ConsumeChallenge(ctx, ceremony, security.HashToken(challengeID))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil, ErrInvalidPasskeyChallenge
+		}
+
+		return nil, nil, err
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(challenge.SessionData, &session); err != nil {
+		return nil, nil, err
+	}
+
+	return challenge, &session, nil
+}
+
+// newWebAuthnUser loads the passkeys of the user for a WebAuthn ceremony.
+func (u *authUsecase) newWebAuthnUser(ctx context.Context, user *domain.User) (*webAuthnUser, error) {
+	credentials, err := u.webAuthnCredentialRepo.GetCredentialsByUserID(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+// webAuthnUser adapts a user and their passkeys to the user of the WebAuthn library.
+// Passkeys flagged as possibly cloned are left out so that they cannot be used.
+type webAuthnUser struct {
+	user        *domain.User
+	credentials []domain.WebAuthnCredential
+}
+
+func (w *webAuthnUser) WebAuthnID() []byte {
+	return []byte(w.user.ID.Hex())
+}
+
+func (w *webAuthnUser) WebAuthnName() string {
+	return w.user.Email
+}
+
+func (w *webAuthnUser) WebAuthnDisplayName() string {
+	if w.user.FullName != "" {
+		return w.user.FullName
+	}
+
+	return w.user.Email
+}
+
+func (w *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(w.credentials))
+	for _, credential := range w.credentials {
+		if credential.CloneWarning {
+			continue
+		}
+
+		transports := make([]protocol.AuthenticatorTransport, 0, len(credential.Transports))
+		for _, transport := range credential.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              credential.CredentialID,
+			PublicKey:       credential.PublicKey,
+			AttestationType: credential.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: credential.BackupEligible,
+				BackupState:    credential.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    credential.AAGUID,
+				SignCount: credential.SignCount,
+			},
+		})
+	}
+
+	return credentials
+}
+
+// credential returns the stored passkey with the given credential ID.
+func (w *webAuthnUser) credential(credentialID []byte) *domain.WebAuthnCredential {
+	for i, credential := range w.credentials {
+		if bytes.Equal(credential.CredentialID, credentialID) {
+			return &w.credentials[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,472 @@
+package usecase
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+// Authenticator data flags, see https://www.w3.org/TR/webauthn-3/#authdata-flags.
+const (
+	authenticatorFlagUserPresent            = 0x01
+	authenticatorFlagUserVerified           = 0x04
+	authenticatorFlagAttestedCredentialData = 0x40
+)
+
+// softwareAuthenticator is a passkey held in memory. It answers the options returned by the
+// usecase the way a browser and a platform authenticator would, without attestation.
+type softwareAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftwareAuthenticator(t *testing.T) *softwareAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate passkey: %v", err)
+	}
+
+	credentialID := make([]byte, 32)
+	_, _ = rand.Read(credentialID)
+
+	return &softwareAuthenticator{key: key, credentialID: credentialID}
+}
+
+func newTestWebAuthn(t *testing.T) *webauthn.WebAuthn {
+	t.Helper()
+
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          testRPID,
+		RPDisplayName: "Test",
+		RPOrigins:     []string{testOrigin},
+	})
+	if err != nil {
+		t.Fatalf("failed to create WebAuthn relying party: %v", err)
+	}
+
+	return webAuthn
+}
+
+// create answers the options of a registration ceremony with a new credential.
+func (a *softwareAuthenticator) create(t *testing.T, options []byte) []byte {
+	t.Helper()
+
+	var creation struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+			User      struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		} `json:"publicKey"`
+	}
+	if err := json.Unmarshal(options, &creation); err != nil {
+		t.Fatalf("failed to decode registration options: %v", err)
+	}
+
+	userHandle, err := base64.RawURLEncoding.DecodeString(creation.PublicKey.User.ID)
+	if err != nil {
+		t.Fatalf("failed to decode user handle: %v", err)
+	}
+	a.userHandle = userHandle
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("failed to encode public key: %v", err)
+	}
+
+	authData := a.authenticatorData(authenticatorFlagAttestedCredentialData)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatalf("failed to encode attestation object: %v", err)
+	}
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encode(clientData(t, protocol.CreateCeremony, creation.PublicKey.Challenge)),
+		"attestationObject": encode(attestationObject),
+	})
+}
+
+// get answers the options of a login ceremony with an assertion signed by the passkey.
+func (a *softwareAuthenticator) get(t *testing.T, options []byte) []byte {
+	t.Helper()
+
+	var assertion struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+		} `json:"publicKey"`
+	}
+	if err := json.Unmarshal(options, &assertion); err != nil {
+		t.Fatalf("failed to decode login options: %v", err)
+	}
+
+	a.signCount++
+
+	authData := a.authenticatorData(0)
+	clientDataJSON := clientData(t, protocol.AssertCeremony, assertion.PublicKey.Challenge)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign assertion: %v", err)
+	}
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encode(clientDataJSON),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(a.userHandle),
+	})
+}
+
+// authenticatorData returns the authenticator data up to the sign counter. The user is always
+// present and verified.
+func (a *softwareAuthenticator) authenticatorData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+
+	authData := append([]byte{}, rpIDHash[:]...)
+	authData = append(authData, authenticatorFlagUserPresent|authenticatorFlagUserVerified|flags)
+
+	return binary.BigEndian.AppendUint32(authData, a.signCount)
+}
+
+func (a *softwareAuthenticator) credential(t *testing.T, response map[string]string) []byte {
+	t.Helper()
+
+	credential, err := json.Marshal(map[string]any{
+		"id":       encode(a.credentialID),
+		"rawId":    encode(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatalf("failed to encode credential: %v", err)
+	}
+
+	return credential
+}
+
+func clientData(t *testing.T, ceremony protocol.CeremonyType, challenge string) []byte {
+	t.Helper()
+
+	clientDataJSON, err := json.Marshal(map[string]any{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    testOrigin,
+	})
+	if err != nil {
+		t.Fatalf("failed to encode client data: %v", err)
+	}
+
+	return clientDataJSON
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// registerPasskey registers the passkey for the signed in user.
+func registerPasskey(
+	t *testing.T,
+	u *testUsecase,
+	authenticator *softwareAuthenticator,
+	accessToken string,
+) *domain.WebAuthnCredential {
+	t.Helper()
+
+	challenge, err := u.BeginPasskeyRegistration(t.Context(), domain.BeginPasskeyRegistrationParams{
+		AccessToken: accessToken,
+	})
+	if err != nil {
+		t.Fatalf("BeginPasskeyRegistration() error = %v", err)
+	}
+
+	credential, err := u.FinishPasskeyRegistration(t.Context(), domain.FinishPasskeyRegistrationParams{
+		AccessToken: accessToken,
+		ChallengeID: challenge.ChallengeID,
+		Credential:  authenticator.create(t, challenge.Options),
+		Name:        "Laptop",
+	})
+	if err != nil {
+		t.Fatalf("FinishPasskeyRegistration() error = %v", err)
+	}
+
+	return credential
+}
+
+// beginPasskeyLogin starts a passkey sign-in and returns the challenge ID and the assertion
+// of the passkey.
+func beginPasskeyLogin(
+	t *testing.T,
+	u *testUsecase,
+	authenticator *softwareAuthenticator,
+	mfaToken string,
+) (string, []byte) {
+	t.Helper()
+
+	challenge, err := u.BeginPasskeyLogin(t.Context(), domain.BeginPasskeyLoginParams{MFAToken: mfaToken})
+	if err != nil {
+		t.Fatalf("BeginPasskeyLogin() error = %v", err)
+	}
+
+	return challenge.ChallengeID, authenticator.get(t, challenge.Options)
+}
+
+func TestPasskey_RegistersAndSignsInWithoutPassword(t *testing.T) {
+	u := newTestUsecase(t, newTestWebAuthn(t))
+
+	user := u.createUser(t, "user@example.com")
+	authenticator := newSoftwareAuthenticator(t)
+
+	credential := registerPasskey(t, u, authenticator, u.signIn(t, user.ID.Hex()))
+	if credential.UserID != user.ID.Hex() || credential.Name != "Laptop" {
+		t.Errorf("registered passkey = %+v, want passkey named Laptop of user %s", credential, user.ID.Hex())
+	}
+
+	// The passkey is discoverable, so the user is found from its user handle.
+	challengeID, assertion := beginPasskeyLogin(t, u, authenticator, "")
+
+	tokens, err := u.FinishPasskeyLogin(t.Context(), domain.FinishPasskeyLoginParams{
+		ChallengeID: challengeID,
+		Credential:  assertion,
+	})
+	if err != nil {
+		t.Fatalf("FinishPasskeyLogin() error = %v", err)
+	}
+
+	if claims := u.accessTokenClaims(t, tokens.AccessToken); claims.UserID != user.ID.Hex() {
+		t.Errorf("access token issued to %q, want %q", claims.UserID, user.ID.Hex())
+	}
+
+	stored, _ := u.passkeys.GetCredentialsByUserID(t.Context(), user.ID.Hex())
+	if len(stored) != 1 || stored[0].SignCount != authenticator.signCount || stored[0].LastUsedAt == nil {
+		t.Errorf("stored passkey = %+v, want sign count %d and last use recorded", stored, authenticator.signCount)
+	}
+}
+
+func TestPasskey_CompletesSignInAsSecondFactor(t *testing.T) {
+	u := newTestUsecase(t, newTestWebAuthn(t))
+
+	passwordHash, err := u.passwordHasher.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	user, err := u.users.CreateUser(t.Context(), &domain.User{
+		Email:        "user@example.com",
+		PasswordHash: passwordHash,
+		Verified:     true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	identity, err := u.identities.CreateIdentity(t.Context(), &domain.Identity{
+		UserID:     user.ID.Hex(),
+		ProviderID: user.Email,
+		Provider:   domain.IdentityProviderEmail,
+		Email:      user.Email,
+	})
+	if err != nil {
+		t.Fatalf("failed to create identity: %v", err)
+	}
+
+	authenticator := newSoftwareAuthenticator(t)
+	registerPasskey(t, u, authenticator, u.signIn(t, user.ID.Hex()))
+
+	// With a passkey registered, the password alone no longer signs the user in.
+	result, err := u.SignIn(t.Context(), domain.SignInParams{
+		Email:    user.Email,
+		Password: "correct horse battery staple",
+	})
+	if err != nil {
+		t.Fatalf("SignIn() error = %v", err)
+	}
+	if result.Tokens != nil || result.MFAToken == "" {
+		t.Fatalf("SignIn() = %+v, want an MFA token only", result)
+	}
+
+	challengeID, assertion := beginPasskeyLogin(t, u, authenticator, result.MFAToken)
+
+	tokens, err := u.FinishPasskeyLogin(t.Context(), domain.FinishPasskeyLoginParams{
+		ChallengeID: challengeID,
+		Credential:  assertion,
+	})
+	if err != nil {
+		t.Fatalf("FinishPasskeyLogin() error = %v", err)
+	}
+
+	if claims := u.accessTokenClaims(t, tokens.AccessToken); claims.UserID != user.ID.Hex() {
+		t.Errorf("access token issued to %q, want %q", claims.UserID, user.ID.Hex())
+	}
+
+	if identity, _ = u.identities.GetIdentityByProvider(
+		t.Context(), user.Email, domain.IdentityProviderEmail,
+	); identity.LastLoginAt.IsZero() {
+		t.Error("last login of the password identity was not recorded")
+	}
+}
+
+func TestPasskey_RejectsPasskeyOfAnotherUserAsSecondFactor(t *testing.T) {
+	u := newTestUsecase(t, newTestWebAuthn(t))
+
+	user := u.createUser(t, "user@example.com")
+	registerPasskey(t, u, newSoftwareAuthenticator(t), u.signIn(t, user.ID.Hex()))
+
+	other := u.createUser(t, "other@example.com")
+	otherAuthenticator := newSoftwareAuthenticator(t)
+	registerPasskey(t, u, otherAuthenticator, u.signIn(t, other.ID.Hex()))
+
+	mfaToken, err := u.AuthUsecase.(*authUsecase).generateMFAToken(t.Context(), user.ID.Hex(), "")
+	if err != nil {
+		t.Fatalf("failed to generate MFA token: %v", err)
+	}
+
+	challengeID, assertion := beginPasskeyLogin(t, u, otherAuthenticator, mfaToken)
+
+	if _, err := u.FinishPasskeyLogin(t.Context(), domain.FinishPasskeyLoginParams{
+		ChallengeID: challengeID,
+		Credential:  assertion,
+	}); !errors.Is(err, ErrInvalidPasskey) {
+		t.Fatalf("FinishPasskeyLogin() error = %v, want %v", err, ErrInvalidPasskey)
+	}
+}
+
+func TestPasskey_ExchangesMFATokenForOneSessionOnly(t *testing.T) {
+	u := newTestUsecase(t, newTestWebAuthn(t))
+
+	user := u.createUser(t, "user@example.com")
+	authenticator := newSoftwareAuthenticator(t)
+	registerPasskey(t, u, authenticator, u.signIn(t, user.ID.Hex()))
+
+	mfaToken, err := u.AuthUsecase.(*authUsecase).generateMFAToken(t.Context(), user.ID.Hex(), "")
+	if err != nil {
+		t.Fatalf("failed to generate MFA token: %v", err)
+	}
+
+	before, _ := u.sessions.ListActiveSessionsByUserID(t.Context(), user.ID.Hex())
+
+	// Every ceremony begun with the same MFA token is valid on its own.
+	firstChallengeID, firstAssertion := beginPasskeyLogin(t, u, authenticator, mfaToken)
+	secondChallengeID, secondAssertion := beginPasskeyLogin(t, u, authenticator, mfaToken)
+
+	if _, err := u.FinishPasskeyLogin(t.Context(), domain.FinishPasskeyLoginParams{
+		ChallengeID: firstChallengeID,
+		Credential:  firstAssertion,
+	}); err != nil {
+		t.Fatalf("FinishPasskeyLogin() error = %v", err)
+	}
+
+	if _, err := u.FinishPasskeyLogin(t.Context(), domain.FinishPasskeyLoginParams{
+		ChallengeID: secondChallengeID,
+		Credential:  secondAssertion,
+	}); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Fatalf("FinishPasskeyLogin() with a consumed MFA token error = %v, want %v", err, ErrInvalidMFAToken)
+	}
+
+	if after, _ := u.sessions.ListActiveSessionsByUserID(t.Context(), user.ID.Hex()); len(after) != len(before)+1 {
+		t.Errorf("got %d new sessions for one MFA token, want 1", len(after)-len(before))
+	}
+}
+
+func TestPasskey_FlagsPasskeyWhenSignCounterDoesNotIncrease(t *testing.T) {
+	u := newTestUsecase(t, newTestWebAuthn(t))
+
+	user := u.createUser(t, "user@example.com")
+	authenticator := newSoftwareAuthenticator(t)
+	registerPasskey(t, u, authenticator, u.signIn(t, user.ID.Hex()))
+
+	challengeID, assertion := beginPasskeyLogin(t, u, authenticator, "")
+	if _, err := u.FinishPasskeyLogin(t.Context(), domain.FinishPasskeyLoginParams{
+		ChallengeID: challengeID,
+		Credential:  assertion,
+	}); err != nil {
+		t.Fatalf("FinishPasskeyLogin() error = %v", err)
+	}
+
+	// A copy of the key starts from an older counter than the authenticator it was taken from.
+	authenticator.signCount = 0
+
+	challengeID, assertion = beginPasskeyLogin(t, u, authenticator, "")
+	if _, err := u.FinishPasskeyLogin(t.Context(), domain.FinishPasskeyLoginParams{
+		ChallengeID: challengeID,
+		Credential:  assertion,
+	}); !errors.Is(err, ErrInvalidPasskey) {
+		t.Fatalf("FinishPasskeyLogin() with a cloned passkey error = %v, want %v", err, ErrInvalidPasskey)
+	}
+
+	stored, _ := u.passkeys.GetCredentialsByUserID(t.Context(), user.ID.Hex())
+	if len(stored) != 1 || !stored[0].CloneWarning {
+		t.Fatalf("stored passkey = %+v, want it flagged as cloned", stored)
+	}
+
+	// The flagged passkey can no longer be used, even with a higher counter.
+	authenticator.signCount = 100
+
+	challengeID, assertion = beginPasskeyLogin(t, u, authenticator, "")
+	if _, err := u.FinishPasskeyLogin(t.Context(), domain.FinishPasskeyLoginParams{
+		ChallengeID: challengeID,
+		Credential:  assertion,
+	}); !errors.Is(err, ErrInvalidPasskey) {
+		t.Fatalf("FinishPasskeyLogin() with a flagged passkey error = %v, want %v", err, ErrInvalidPasskey)
+	}
+}
+
+func TestPasskey_RejectsConsumedChallenge(t *testing.T) {
+	u := newTestUsecase(t, newTestWebAuthn(t))
+
+	user := u.createUser(t, "user@example.com")
+	authenticator := newSoftwareAuthenticator(t)
+	registerPasskey(t, u, authenticator, u.signIn(t, user.ID.Hex()))
+
+	challengeID, assertion := beginPasskeyLogin(t, u, authenticator, "")
+	params := domain.FinishPasskeyLoginParams{ChallengeID: challengeID, Credential: assertion}
+
+	if _, err := u.FinishPasskeyLogin(t.Context(), params); err != nil {
+		t.Fatalf("FinishPasskeyLogin() error = %v", err)
+	}
+
+	if _, err := u.FinishPasskeyLogin(t.Context(), params); !errors.Is(err, ErrInvalidPasskeyChallenge) {
+		t.Fatalf("FinishPasskeyLogin() with a used challenge error = %v, want %v", err, ErrInvalidPasskeyChallenge)
+	}
+}
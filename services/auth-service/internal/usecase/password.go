@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
+)
+
+const passwordResetTokenSize = 32
+
+var (
+	ErrInvalidResetToken   = errors.New("invalid or expired password reset token")
+	ErrIncorrectPassword   = errors.New("incorrect current password")
+	ErrPasswordCompromised = errors.New("password has appeared in a data breach")
+)
+
+func (u *authUsecase) RequestPasswordReset(ctx context.Context, params domain.RequestPasswordResetParams) error {
+	user, err := u.userRepo.GetUserByEmail(ctx, params.Email)
+	if err != nil {
+		// Unknown addresses are not reported to avoid leaking which emails are registered.
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+
+		return err
+	}
+
+	// Only the most recently requested token stays valid.
+	if err := u.oneTimeTokenRepo.DeleteTokensByUserID(
+		ctx,
+		user.ID.Hex(),
+		domain.TokenPurposePasswordReset,
+	); err != nil {
+		return err
+	}
+
+	token, err := security.GenerateRandomToken(passwordResetTokenSize)
+	if err != nil {
+		return err
+	}
+
+	if _, err := u.oneTimeTokenRepo.CreateToken(ctx, &domain.OneTimeToken{
+		UserID:    user.ID.Hex(),
+		Purpose:   domain.TokenPurposePasswordReset,
+		TokenHash: security.HashToken(token),
+		ExpiresAt: time.Now().Add(u.authServiceCfg.PasswordReset.TokenExpiresIn),
+	}); err != nil {
+		return err
+	}
+
+	resetURL, err := withToken(u.authServiceCfg.PasswordReset.URL, token)
+	if err != nil {
+		return err
+	}
+
+	// The email is sent in the background and a failure to send is not reported either, since
+	// unknown addresses never wait for the mail server and never fail.
+	u.sendInBackground(
+		ctx,
+		user.ID.Hex(),
+		newPasswordResetEmail(user.Email, resetURL, u.authServiceCfg.PasswordReset.TokenExpiresIn),
+	)
+
+	return nil
+}
+
+func (u *authUsecase) ResetPassword(ctx context.Context, params domain.ResetPasswordParams) error {
+	tokenHash := security.HashToken(params.Token)
+
+	token, err := u.oneTimeTokenRepo.GetToken(ctx, domain.TokenPurposePasswordReset, tokenHash)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrInvalidResetToken
+		}
+
+		return err
+	}
+
+	user, err := u.userRepo.GetUser(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrInvalidResetToken
+		}
+
+		return err
+	}
+
+	// The policy is checked before the token is consumed so that a rejected password
+	// does not force the user to request another reset email.
+	if err := u.validatePassword(params.NewPassword, user.Email, user.FullName); err != nil {
+		return err
+	}
+
+	if _, err := u.oneTimeTokenRepo.ConsumeToken(ctx, domain.TokenPurposePasswordReset, tokenHash); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrInvalidResetToken
+		}
+
+		return err
+	}
+
+	passwordHash, err := u.passwordHasher.Hash(params.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	passwordChangeRequired := false
+	noRecoveryFamilyID := ""
+	if _, err := u.userRepo.UpdateUser(ctx, token.UserID, domain.UpdateUserParams{
+		PasswordHash:           &passwordHash,
+		PasswordChangeRequired: &passwordChangeRequired,
+		RecoveryFamilyID:       &noRecoveryFamilyID,
+	}); err != nil {
+		return err
+	}
+
+	// Anyone holding a session obtained with the old password is signed out.
+	sessions, err := u.sessionRepo.RevokeSessionsByUserID(ctx, token.UserID)
+	if err != nil {
+		return err
+	}
+
+	return u.revokeAccessTokens(ctx, sessions)
+}
+
+func (u *authUsecase) ChangePassword(ctx context.Context, params domain.ChangePasswordParams) error {
+	session, err := u.authenticateSession(ctx, params.AccessToken)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.userRepo.GetUser(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrInvalidToken
+		}
+
+		return err
+	}
+
+	// A user who signed in with a recovery code has lost their password and a user created
+	// through an OAuth provider has none, so neither is asked for the current password. The
+	// former only from the session that redeemed the code, not from any other session.
+	recoverySession := user.PasswordChangeRequired && user.RecoveryFamilyID == sessionFamilyID(session)
+	if !recoverySession && user.PasswordHash != "" {
+		if ok, err := u.passwordHasher.Verify(params.CurrentPassword, user.PasswordHash); err != nil {
+			return err
+		} else if !ok {
+			return ErrIncorrectPassword
+		}
+	}
+
+	if err := u.validatePassword(params.NewPassword, user.Email, user.FullName); err != nil {
+		return err
+	}
+
+	passwordHash, err := u.passwordHasher.Hash(params.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	passwordChangeRequired := false
+	noRecoveryFamilyID := ""
+	if _, err := u.userRepo.UpdateUser(ctx, user.ID.Hex(), domain.UpdateUserParams{
+		PasswordHash:           &passwordHash,
+		PasswordChangeRequired: &passwordChangeRequired,
+		RecoveryFamilyID:       &noRecoveryFamilyID,
+	}); err != nil {
+		return err
+	}
+
+	if params.RevokeOtherSessions {
+		sessions, err := u.sessionRepo.RevokeOtherSessions(ctx, user.ID.Hex(), sessionFamilyID(session))
+		if err != nil {
+			return err
+		}
+
+		return u.revokeAccessTokens(ctx, sessions)
+	}
+
+	return nil
+}
+
+// validatePassword checks a new password against the password policy and the known breached passwords.
+func (u *authUsecase) validatePassword(password string, personalInfo ...string) error {
+	if err := u.passwordPolicy.Validate(password, personalInfo...); err != nil {
+		return err
+	}
+
+	if u.breachChecker.IsCompromised(password) {
+		return ErrPasswordCompromised
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
+)
+
+const recoveryCodeCount = 10
+
+var ErrInvalidRecoveryCode = errors.New("invalid recovery code")
+
+func (u *authUsecase) GenerateRecoveryCodes(
+	ctx context.Context,
+	params domain.GenerateRecoveryCodesParams,
+) ([]string, error) {
+	user, err := u.authenticateUser(ctx, params.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.TOTPEnabled {
+		return nil, ErrMFANotEnrolled
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	codeHashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		code, err := security.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		codeHash, err := u.passwordHasher.Hash(code)
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+		codeHashes = append(codeHashes, codeHash)
+	}
+
+	// Generating a new set invalidates every code from the previous one.
+	if _, err := u.userRepo.UpdateUser(ctx, user.ID.Hex(), domain.UpdateUserParams{
+		RecoveryCodes: &codeHashes,
+	}); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (u *authUsecase) RedeemRecoveryCode(
+	ctx context.Context,
+	params domain.RedeemRecoveryCodeParams,
+) (*domain.RecoveryCodeRedemption, error) {
+	user, err := u.userRepo.GetUserByEmail(ctx, params.Email)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidRecoveryCode
+		}
+
+		return nil, err
+	}
+
+	// Recovery codes stand in for the authenticator, so they are useless without one.
+	if !user.TOTPEnabled {
+		return nil, ErrInvalidRecoveryCode
+	}
+
+	code := strings.ToLower(strings.TrimSpace(params.Code))
+
+	var matchedHash string
+	for _, codeHash := range user.RecoveryCodes {
+		ok, err := u.passwordHasher.Verify(code, codeHash)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			matchedHash = codeHash
+			break
+		}
+	}
+
+	if matchedHash == "" {
+		return nil, ErrInvalidRecoveryCode
+	}
+
+	if err := u.userRepo.RemoveRecoveryCode(ctx, user.ID.Hex(), matchedHash); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidRecoveryCode
+		}
+
+		return nil, err
+	}
+
+	// Whoever took over the account may still be signed in, so every existing session is
+	// signed out.
+	sessions, err := u.sessionRepo.RevokeSessionsByUserID(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.revokeAccessTokens(ctx, sessions); err != nil {
+		return nil, err
+	}
+
+	session, err := u.startSession(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	// Recovery codes are a break-glass path, so the lost authenticator is unenrolled along with
+	// the remaining codes, and the user must choose a new password before anything else. Only
+	// the session created here may change the password without the current one.
+	passwordChangeRequired := true
+	totpEnabled := false
+	emptySecret := ""
+	noRecoveryCodes := []string{}
+	if _, err := u.userRepo.UpdateUser(ctx, user.ID.Hex(), domain.UpdateUserParams{
+		TOTPSecret:             &emptySecret,
+		TOTPEnabled:            &totpEnabled,
+		RecoveryCodes:          &noRecoveryCodes,
+		PasswordChangeRequired: &passwordChangeRequired,
+		RecoveryFamilyID:       &session.FamilyID,
+	}); err != nil {
+		return nil, err
+	}
+
+	identityID, err := u.emailIdentityID(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := u.issueTokens(ctx, user.ID.Hex(), session.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	u.recordLastLogin(ctx, identityID)
+
+	return &domain.RecoveryCodeRedemption{
+		Tokens:                 tokens,
+		PasswordChangeRequired: passwordChangeRequired,
+	}, nil
+}
+
+func (u *authUsecase) CountRecoveryCodes(ctx context.Context, params domain.CountRecoveryCodesParams) (int, error) {
+	user, err := u.authenticateUser(ctx, params.AccessToken)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(user.RecoveryCodes), nil
+}
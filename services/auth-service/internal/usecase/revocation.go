@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+func (u *authUsecase) RevokeSession(ctx context.Context, params domain.RevokeSessionParams) error {
+	session, err := u.sessionRepo.GetSession(ctx, params.SessionID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrSessionNotFound
+		}
+
+		return err
+	}
+
+	// As with signing out, the whole family is revoked so that the session cannot be
+	// continued with a refresh token issued to a later generation.
+	sessions, err := u.sessionRepo.RevokeSessionFamily(ctx, sessionFamilyID(session))
+	if err != nil {
+		return err
+	}
+
+	return u.revokeAccessTokens(ctx, sessions)
+}
+
+func (u *authUsecase) ListRevokedSessions(
+	ctx context.Context,
+	params domain.ListRevokedSessionsParams,
+) ([]domain.RevokedSession, error) {
+	return u.revokedSessionRepo.ListRevokedSessions(ctx, params.Since)
+}
+
+// revokeAccessTokens records the revoked sessions whose access tokens have not expired yet,
+// so that those tokens are rejected right away rather than when they expire.
+func (u *authUsecase) revokeAccessTokens(ctx context.Context, sessions []*domain.Session) error {
+	now := time.Now()
+
+	revokedSessions := make([]*domain.RevokedSession, 0, len(sessions))
+	revocations := make([]auth.Revocation, 0, len(sessions))
+	for _, session := range sessions {
+		if !session.AccessTokenExpiresAt.After(now) {
+			continue
+		}
+
+		revokedSessions = append(revokedSessions, &domain.RevokedSession{
+			SessionID: session.ID.Hex(),
+			ExpiresAt: session.AccessTokenExpiresAt,
+			RevokedAt: now,
+		})
+		revocations = append(revocations, auth.Revocation{
+			SessionID: session.ID.Hex(),
+			ExpiresAt: session.AccessTokenExpiresAt,
+			RevokedAt: now,
+		})
+	}
+
+	if err := u.revokedSessionRepo.CreateRevokedSessions(ctx, revokedSessions); err != nil {
+		return err
+	}
+
+	u.revocations.Add(revocations...)
+
+	return nil
+}
+
+// revocationSource provides the revoked sessions recorded by every instance of the auth
+// service to the revocation list.
+type revocationSource struct {
+	revokedSessionRepo domain.RevokedSessionRepository
+}
+
+// NewRevocationSource creates a revocation source backed by the revoked sessions in the database.
+func NewRevocationSource(revokedSessionRepo domain.RevokedSessionRepository) auth.RevocationSource {
+	return &revocationSource{revokedSessionRepo: revokedSessionRepo}
+}
+
+func (s *revocationSource) ListRevocations(ctx context.Context, since time.Time) ([]auth.Revocation, error) {
+	revokedSessions, err := s.revokedSessionRepo.ListRevokedSessions(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	revocations := make([]auth.Revocation, 0, len(revokedSessions))
+	for _, revokedSession := range revokedSessions {
+		revocations = append(revocations, auth.Revocation{
+			SessionID: revokedSession.SessionID,
+			ExpiresAt: revokedSession.ExpiresAt,
+			RevokedAt: revokedSession.RevokedAt,
+		})
+	}
+
+	return revocations, nil
+}
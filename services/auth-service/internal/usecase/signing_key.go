@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
+)
+
+var (
+	ErrKeyRotationDisabled    = errors.New("signing key rotation requires an asymmetric algorithm")
+	ErrSigningKeyNotFound     = errors.New("signing key not found")
+	ErrSigningKeyNotStaged    = errors.New("signing key is not staged")
+	ErrRetireActiveSigningKey = errors.New("active signing key cannot be retired")
+)
+
+func (u *authUsecase) GetJWKS(_ context.Context) (*auth.JWKS, error) {
+	return u.accessTokenKeys.JWKS()
+}
+
+func (u *authUsecase) ListSigningKeys(ctx context.Context) ([]auth.ManagedKey, error) {
+	// Keys staged or promoted through another instance are only known after a refresh.
+	if err := u.accessTokenKeys.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	return u.accessTokenKeys.ManagedKeys(), nil
+}
+
+func (u *authUsecase) StageSigningKey(ctx context.Context) (*auth.ManagedKey, error) {
+	algorithm := u.authServiceCfg.Token.AccessTokenKeys.Algorithm
+	if algorithm == auth.AlgorithmHS256 {
+		return nil, ErrKeyRotationDisabled
+	}
+
+	key, err := auth.GenerateKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.accessTokenKeys.Stage(ctx, key)
+}
+
+func (u *authUsecase) PromoteSigningKey(ctx context.Context, params domain.PromoteSigningKeyParams) error {
+	if err := u.accessTokenKeys.Promote(ctx, params.KeyID); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrKeyNotFound):
+			return ErrSigningKeyNotFound
+		case errors.Is(err, auth.ErrKeyNotStaged):
+			return ErrSigningKeyNotStaged
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *authUsecase) RetireSigningKey(ctx context.Context, params domain.RetireSigningKeyParams) error {
+	if err := u.accessTokenKeys.Retire(ctx, params.KeyID); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrKeyNotFound), errors.Is(err, mongo.ErrNoDocuments):
+			return ErrSigningKeyNotFound
+		case errors.Is(err, auth.ErrRetireActiveKey):
+			return ErrRetireActiveSigningKey
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// signingKeyStore persists the access token signing keys in the database, encrypting the
+// private keys before they are stored.
+type signingKeyStore struct {
+	signingKeyRepo domain.SigningKeyRepository
+	encryptor      *security.Encryptor
+}
+
+// NewSigningKeyStore creates a key store that shares the access token signing keys between
+// the instances of the auth service.
+func NewSigningKeyStore(signingKeyRepo domain.SigningKeyRepository, encryptor *security.Encryptor) auth.KeyStore {
+	return &signingKeyStore{
+		signingKeyRepo: signingKeyRepo,
+		encryptor:      encryptor,
+	}
+}
+
+func (s *signingKeyStore) LoadKeys(ctx context.Context) ([]auth.ManagedKey, error) {
+	signingKeys, err := s.signingKeyRepo.GetSigningKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]auth.ManagedKey, 0, len(signingKeys))
+	for _, signingKey := range signingKeys {
+		privateKeyPEM, err := s.encryptor.Decrypt(signingKey.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt signing key %q: %w", signingKey.KeyID, err)
+		}
+
+		privateKey, err := auth.ParsePrivateKeyPEM([]byte(privateKeyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key %q: %w", signingKey.KeyID, err)
+		}
+
+		key, err := auth.NewPrivateKey(signingKey.Algorithm, privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load signing key %q: %w", signingKey.KeyID, err)
+		}
+
+		managedKey := auth.ManagedKey{
+			Key:       key,
+			Status:    signingKey.Status,
+			CreatedAt: signingKey.CreatedAt,
+		}
+		if signingKey.PromotedAt != nil {
+			managedKey.PromotedAt = *signingKey.PromotedAt
+		}
+		if signingKey.ExpiresAt != nil {
+			managedKey.ExpiresAt = *signingKey.ExpiresAt
+		}
+
+		keys = append(keys, managedKey)
+	}
+
+	return keys, nil
+}
+
+func (s *signingKeyStore) SaveKey(ctx context.Context, key auth.ManagedKey) error {
+	privateKeyPEM, err := auth.MarshalPrivateKeyPEM(key.Key.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	encryptedPrivateKey, err := s.encryptor.Encrypt(string(privateKeyPEM))
+	if err != nil {
+		return err
+	}
+
+	signingKey := &domain.SigningKey{
+		KeyID:      key.Key.ID,
+		Algorithm:  key.Key.Algorithm,
+		Status:     key.Status,
+		PrivateKey: encryptedPrivateKey,
+		CreatedAt:  key.CreatedAt,
+	}
+	if !key.PromotedAt.IsZero() {
+		signingKey.PromotedAt = &key.PromotedAt
+	}
+	if !key.ExpiresAt.IsZero() {
+		signingKey.ExpiresAt = &key.ExpiresAt
+	}
+
+	return s.signingKeyRepo.UpsertSigningKey(ctx, signingKey)
+}
+
+func (s *signingKeyStore) DeleteKey(ctx context.Context, keyID string) error {
+	return s.signingKeyRepo.DeleteSigningKey(ctx, keyID)
+}
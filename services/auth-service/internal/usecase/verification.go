@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/vasapolrittideah/optimize-api/services/auth-service/internal/domain"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
+)
+
+const verificationCodeDigits = 6
+
+var (
+	ErrEmailNotVerified        = errors.New("email not verified")
+	ErrInvalidVerificationCode = errors.New("invalid verification code")
+)
+
+// VerifyEmail verifies the email address of the user with the code sent to it. Unknown and
+// already verified addresses, expired codes and codes that were guessed too many times all
+// fail with ErrInvalidVerificationCode, so that the response does not reveal which emails
+// are registered.
+func (u *authUsecase) VerifyEmail(ctx context.Context, params domain.VerifyEmailParams) error {
+	user, err := u.userRepo.GetUserByEmail(ctx, params.Email)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrInvalidVerificationCode
+		}
+
+		return err
+	}
+
+	if user.Verified {
+		return ErrInvalidVerificationCode
+	}
+
+	if user.VerificationCode == "" || time.Now().After(user.VerificationCodeExpiresAt) {
+		return ErrInvalidVerificationCode
+	}
+
+	// Attempts are counted before the code is checked so that concurrent guesses
+	// cannot exceed the limit.
+	user, err = u.userRepo.IncrementVerificationAttempts(ctx, user.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	if user.VerificationAttempts > u.authServiceCfg.Verification.MaxAttempts {
+		return ErrInvalidVerificationCode
+	}
+
+	if ok, err := u.passwordHasher.Verify(params.Code, user.VerificationCode); err != nil {
+		return err
+	} else if !ok {
+		return ErrInvalidVerificationCode
+	}
+
+	verified := true
+	emptyCode := ""
+	noAttempts := 0
+	if _, err := u.userRepo.UpdateUser(ctx, user.ID.Hex(), domain.UpdateUserParams{
+		Verified:             &verified,
+		VerificationCode:     &emptyCode,
+		VerificationAttempts: &noAttempts,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ResendVerification sends a new verification code. Unknown and already verified addresses,
+// and requests made before the resend interval has passed, succeed without sending anything,
+// so that the response does not reveal which emails are registered.
+func (u *authUsecase) ResendVerification(ctx context.Context, params domain.ResendVerificationParams) error {
+	user, err := u.userRepo.GetUserByEmail(ctx, params.Email)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+
+		return err
+	}
+
+	if user.Verified {
+		return nil
+	}
+
+	if time.Since(user.VerificationCodeSentAt) < u.authServiceCfg.Verification.ResendInterval {
+		return nil
+	}
+
+	code, codeHash, expiresAt, err := u.newVerificationCode()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	noAttempts := 0
+	if _, err := u.userRepo.UpdateUser(ctx, user.ID.Hex(), domain.UpdateUserParams{
+		VerificationCode:          &codeHash,
+		VerificationCodeExpiresAt: &expiresAt,
+		VerificationCodeSentAt:    &now,
+		VerificationAttempts:      &noAttempts,
+	}); err != nil {
+		return err
+	}
+
+	// A failure to send is not reported either, since unknown addresses never fail.
+	if err := u.sendVerificationEmail(ctx, user.Email, code); err != nil {
+		u.logger.Error().Err(err).Str("user_id", user.ID.Hex()).Msg("failed to send verification email")
+	}
+
+	return nil
+}
+
+// sendVerificationEmail delivers the verification code to the user.
+func (u *authUsecase) sendVerificationEmail(ctx context.Context, email, code string) error {
+	return u.mailer.Send(ctx, newVerificationEmail(email, code, u.authServiceCfg.Verification.CodeExpiresIn))
+}
+
+// newVerificationCode generates a verification code along with its hash and expiry time.
+func (u *authUsecase) newVerificationCode() (string, string, time.Time, error) {
+	code, err := security.GenerateNumericCode(verificationCodeDigits)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	codeHash, err := u.passwordHasher.Hash(code)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	return code, codeHash, time.Now().Add(u.authServiceCfg.Verification.CodeExpiresIn), nil
+}
@@ -3,6 +3,7 @@ package authclient
 import (
 	"google.golang.org/grpc"
 
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
 	"github.com/vasapolrittideah/optimize-api/shared/discovery"
 	authpbv1 "github.com/vasapolrittideah/optimize-api/shared/protos/auth/v1"
 )
@@ -10,6 +11,10 @@ import (
 type AuthServiceClient struct {
 	Client authpbv1.AuthServiceClient
 	conn   *grpc.ClientConn
+
+	introspectionCache *introspectionCache
+	revocations        *auth.RevocationList
+	operatorToken      string
 }
 
 func NewAuthServiceClient(serviceName string, consulRegistry *discovery.ConsulRegistry) (*AuthServiceClient, error) {
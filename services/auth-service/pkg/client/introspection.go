@@ -0,0 +1,128 @@
+package authclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	authpbv1 "github.com/vasapolrittideah/optimize-api/shared/protos/auth/v1"
+)
+
+// maxIntrospectionCacheEntries bounds the memory used by the introspection cache. Once it is
+// full and no entry has expired, new results are not cached until entries expire.
+const maxIntrospectionCacheEntries = 10000
+
+// EnableIntrospectionCache caches active introspection results in memory for up to ttl, and
+// never past the expiry of the token. Revoking a session only takes effect for a cached token
+// once its entry expires, so ttl bounds how long a revoked token may still be accepted, unless
+// the client also uses a revocation list.
+func (c *AuthServiceClient) EnableIntrospectionCache(ttl time.Duration) {
+	c.introspectionCache = &introspectionCache{
+		ttl:     ttl,
+		entries: make(map[string]introspectionCacheEntry),
+	}
+}
+
+// IntrospectToken asks the auth service whether the access token is active and who it was
+// issued to, answering from the introspection cache when it is enabled.
+func (c *AuthServiceClient) IntrospectToken(
+	ctx context.Context,
+	token string,
+) (*authpbv1.IntrospectTokenResponse, error) {
+	if c.introspectionCache == nil {
+		return c.Client.IntrospectToken(ctx, &authpbv1.IntrospectTokenRequest{Token: token})
+	}
+
+	key := introspectionCacheKey(token)
+	if resp, ok := c.introspectionCache.get(key); ok {
+		if c.revocations != nil && c.revocations.IsRevoked(resp.GetSessionId()) {
+			return &authpbv1.IntrospectTokenResponse{}, nil
+		}
+
+		return resp, nil
+	}
+
+	resp, err := c.Client.IntrospectToken(ctx, &authpbv1.IntrospectTokenRequest{Token: token})
+	if err != nil {
+		return nil, err
+	}
+
+	// Inactive results are not cached, so that requests with made up tokens cannot fill the cache.
+	if resp.GetActive() {
+		c.introspectionCache.set(key, resp)
+	}
+
+	return resp, nil
+}
+
+type introspectionCacheEntry struct {
+	resp      *authpbv1.IntrospectTokenResponse
+	expiresAt time.Time
+}
+
+// introspectionCache holds introspection results keyed by the hash of the token, so that the
+// tokens themselves are not kept in memory.
+type introspectionCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]introspectionCacheEntry
+}
+
+func (c *introspectionCache) get(key string) (*authpbv1.IntrospectTokenResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !time.Now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return entry.resp, true
+}
+
+func (c *introspectionCache) set(key string, resp *authpbv1.IntrospectTokenResponse) {
+	now := time.Now()
+
+	expiresAt := now.Add(c.ttl)
+	if resp.GetExp() != nil && resp.GetExp().AsTime().Before(expiresAt) {
+		expiresAt = resp.GetExp().AsTime()
+	}
+
+	if !now.Before(expiresAt) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxIntrospectionCacheEntries {
+		for entryKey, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, entryKey)
+			}
+		}
+
+		if len(c.entries) >= maxIntrospectionCacheEntries {
+			return
+		}
+	}
+
+	c.entries[key] = introspectionCacheEntry{
+		resp:      resp,
+		expiresAt: expiresAt,
+	}
+}
+
+func introspectionCacheKey(token string) string {
+	digest := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,55 @@
+package authclient
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
+	"github.com/vasapolrittideah/optimize-api/shared/operator"
+	authpbv1 "github.com/vasapolrittideah/optimize-api/shared/protos/auth/v1"
+)
+
+// ListRevocations lists the sessions revoked since a point in time, so that the client can be
+// the source of an auth.RevocationList kept in sync with the auth service. It requires the
+// operator token set with UseOperatorToken.
+func (c *AuthServiceClient) ListRevocations(ctx context.Context, since time.Time) ([]auth.Revocation, error) {
+	if c.operatorToken != "" {
+		ctx = operator.WithToken(ctx, c.operatorToken)
+	}
+
+	req := &authpbv1.ListRevokedSessionsRequest{}
+	if !since.IsZero() {
+		req.Since = timestamppb.New(since)
+	}
+
+	resp, err := c.Client.ListRevokedSessions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	revocations := make([]auth.Revocation, 0, len(resp.RevokedSessions))
+	for _, revokedSession := range resp.RevokedSessions {
+		revocations = append(revocations, auth.Revocation{
+			SessionID: revokedSession.SessionId,
+			ExpiresAt: revokedSession.ExpiresAt.AsTime(),
+			RevokedAt: revokedSession.RevokedAt.AsTime(),
+		})
+	}
+
+	return revocations, nil
+}
+
+// UseRevocationList makes IntrospectToken report the tokens of revoked sessions as inactive,
+// even when an active result was cached before the session was revoked. The list is usually
+// synced from this client, so that a revocation reaches the cache within one poll interval.
+func (c *AuthServiceClient) UseRevocationList(revocations *auth.RevocationList) {
+	c.revocations = revocations
+}
+
+// UseOperatorToken sets the operator token sent with the RPCs that only operators may call,
+// such as the ListRevokedSessions call made by ListRevocations.
+func (c *AuthServiceClient) UseOperatorToken(token string) {
+	c.operatorToken = token
+}
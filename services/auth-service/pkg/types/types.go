@@ -1,15 +1,42 @@
 package authtypes
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/vasapolrittideah/optimize-api/shared/auth"
+)
 
 type Tokens struct {
 	AccessToken  string
 	RefreshToken string
 }
 
+// JWTClaims are the claims of the access and refresh tokens issued by the auth service. Roles
+// and Scope describe what the user may do, so that services can authorize requests from the
+// token alone. Scope is a space-delimited list of scopes.
 type JWTClaims struct {
 	jwt.RegisteredClaims
 
-	UserID    string `json:"user_id"`
-	SessionID string `json:"session_id"`
+	UserID    string   `json:"user_id"`
+	SessionID string   `json:"session_id"`
+	TokenType string   `json:"token_type,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+}
+
+// Scopes returns the scopes granted by the token.
+func (c *JWTClaims) Scopes() []string {
+	return auth.ParseScope(c.Scope)
+}
+
+// HasRole reports whether the token was issued to a user with the role.
+func (c *JWTClaims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// HasScopes reports whether the token grants every one of the scopes.
+func (c *JWTClaims) HasScopes(scopes ...string) bool {
+	return auth.HasScopes(c.Scope, scopes...)
 }
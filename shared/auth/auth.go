@@ -4,6 +4,14 @@ import "github.com/golang-jwt/jwt/v5"
 
 // Authenticator defines the interface for managing authentication.
 type Authenticator interface {
-	GenerateToken(claims jwt.Claims, secret string) (string, error)
-	ValidateToken(token, secret string) (*jwt.Token, error)
+	GenerateToken(claims jwt.Claims, keys KeyProvider) (string, error)
+	ValidateToken(token string, keys KeyProvider) (*jwt.Token, error)
+}
+
+// KeyProvider provides the key that tokens are signed with and the keys they are verified with.
+// It is implemented by KeySet for a fixed set of keys and by KeyManager for rotated keys.
+type KeyProvider interface {
+	SigningKey() (*Key, error)
+	Key(keyID string) (*Key, error)
+	Algorithms() []string
 }
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Token types, carried in the token_type claim so that a refresh token cannot be used as an
+// access token and the other way around.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+// ParseClaims validates the token and decodes its claims into a value of type T, usually a
+// struct that embeds jwt.RegisteredClaims and adds the private claims of the token.
+func ParseClaims[T any](authenticator Authenticator, token string, keys KeyProvider) (*T, error) {
+	parsed, err := authenticator.ValidateToken(token, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// The claims are validated as a map and decoded into T through their JSON encoding, which
+	// is how they were encoded in the token in the first place.
+	data, err := json.Marshal(parsed.Claims)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims T
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("failed to decode claims: %w", err)
+	}
+
+	return &claims, nil
+}
+
+// ParseScope splits a scope claim, a space-delimited list of scopes as described in RFC 8693.
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// FormatScope joins scopes into a scope claim, leaving out duplicates.
+func FormatScope(scopes []string) string {
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if scope != "" && !slices.Contains(unique, scope) {
+			unique = append(unique, scope)
+		}
+	}
+
+	return strings.Join(unique, " ")
+}
+
+// HasScopes reports whether the scope claim grants every one of the required scopes.
+func HasScopes(scope string, required ...string) bool {
+	return RequireScopes(scope, required...) == nil
+}
+
+// RequireScopes returns an error naming the required scopes that the scope claim does not grant.
+func RequireScopes(scope string, required ...string) error {
+	granted := ParseScope(scope)
+
+	var missing []string
+	for _, requiredScope := range required {
+		if !slices.Contains(granted, requiredScope) {
+			missing = append(missing, requiredScope)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %s", ErrInsufficientScope, strings.Join(missing, " "))
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// JWK key types and the curves used by the supported signing algorithms.
+const (
+	jwkKeyTypeRSA   = "RSA"
+	jwkKeyTypeEC    = "EC"
+	jwkKeyTypeOKP   = "OKP"
+	jwkCurveP256    = "P-256"
+	jwkCurveEd25519 = "Ed25519"
+	jwkUseSignature = "sig"
+)
+
+var ErrInvalidJWK = errors.New("invalid JSON Web Key")
+
+// JWK is a public key in JSON Web Key format, as described in RFC 7517.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set, the document services fetch to verify tokens without sharing
+// a secret with the service that issues them.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWKS creates a JSON Web Key Set from the public halves of the given keys. Secret keys
+// are never published and are left out.
+func NewJWKS(keys []*Key) (*JWKS, error) {
+	jwks := &JWKS{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		if key.Algorithm == AlgorithmHS256 {
+			continue
+		}
+
+		jwk, err := NewJWK(key)
+		if err != nil {
+			return nil, err
+		}
+
+		jwks.Keys = append(jwks.Keys, *jwk)
+	}
+
+	return jwks, nil
+}
+
+// NewJWK converts the public key of an asymmetric key to JSON Web Key format.
+func NewJWK(key *Key) (*JWK, error) {
+	jwk := &JWK{
+		KeyID:     key.ID,
+		Use:       jwkUseSignature,
+		Algorithm: key.Algorithm,
+	}
+
+	switch publicKey := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = jwkKeyTypeRSA
+		jwk.N = encodeJWKValue(publicKey.N.Bytes())
+		jwk.E = encodeJWKValue(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		if publicKey.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%w: unsupported curve %s", ErrInvalidJWK, publicKey.Curve.Params().Name)
+		}
+
+		jwk.KeyType = jwkKeyTypeEC
+		jwk.Curve = jwkCurveP256
+		jwk.X = encodeJWKValue(publicKey.X.FillBytes(make([]byte, 32)))
+		jwk.Y = encodeJWKValue(publicKey.Y.FillBytes(make([]byte, 32)))
+	case ed25519.PublicKey:
+		jwk.KeyType = jwkKeyTypeOKP
+		jwk.Curve = jwkCurveEd25519
+		jwk.X = encodeJWKValue(publicKey)
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %T", ErrInvalidJWK, key.PublicKey)
+	}
+
+	return jwk, nil
+}
+
+// KeySet creates a verification key set from the keys of the JSON Web Key Set. Keys that are
+// not meant for signatures are skipped.
+func (s *JWKS) KeySet() (*KeySet, error) {
+	keys := make([]*Key, 0, len(s.Keys))
+	for _, jwk := range s.Keys {
+		if jwk.Use != "" && jwk.Use != jwkUseSignature {
+			continue
+		}
+
+		key, err := jwk.Key()
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(keys...), nil
+}
+
+// Key converts the JSON Web Key to a verification key. The key keeps the ID it was published
+// with, so that tokens are matched to it by their kid header.
+func (k *JWK) Key() (*Key, error) {
+	var (
+		publicKey any
+		err       error
+	)
+	switch k.KeyType {
+	case jwkKeyTypeRSA:
+		publicKey, err = k.rsaPublicKey()
+	case jwkKeyTypeEC:
+		publicKey, err = k.ecdsaPublicKey()
+	case jwkKeyTypeOKP:
+		publicKey, err = k.ed25519PublicKey()
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %q", ErrInvalidJWK, k.KeyType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkKeyAlgorithm(k.Algorithm, publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		ID:        k.KeyID,
+		Algorithm: k.Algorithm,
+		PublicKey: publicKey,
+	}, nil
+}
+
+func (k *JWK) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := decodeJWKValue(k.N)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := decodeJWKValue(k.E)
+	if err != nil {
+		return nil, err
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("%w: invalid RSA public key", ErrInvalidJWK)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
+
+func (k *JWK) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	if k.Curve != jwkCurveP256 {
+		return nil, fmt.Errorf("%w: unsupported curve %q", ErrInvalidJWK, k.Curve)
+	}
+
+	x, err := decodeJWKValue(k.X)
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := decodeJWKValue(k.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	// The point is checked through crypto/ecdh, which rejects points that are not on the curve.
+	if len(x) != 32 || len(y) != 32 {
+		return nil, fmt.Errorf("%w: invalid P-256 point", ErrInvalidJWK)
+	}
+	if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidJWK, err)
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}, nil
+}
+
+func (k *JWK) ed25519PublicKey() (ed25519.PublicKey, error) {
+	if k.Curve != jwkCurveEd25519 {
+		return nil, fmt.Errorf("%w: unsupported curve %q", ErrInvalidJWK, k.Curve)
+	}
+
+	x, err := decodeJWKValue(k.X)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: invalid Ed25519 public key", ErrInvalidJWK)
+	}
+
+	return ed25519.PublicKey(x), nil
+}
+
+func encodeJWKValue(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+func decodeJWKValue(value string) ([]byte, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidJWK, err)
+	}
+
+	return decoded, nil
+}
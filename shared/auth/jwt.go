@@ -1,30 +1,50 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+var ErrTokenRevoked = errors.New("token has been revoked")
+
 // JWTAuthenticator represents a JWT based authenticator.
 type JWTAuthenticator struct {
-	audience string
-	issuer   string
+	audience    string
+	issuer      string
+	revocations *RevocationList
 }
 
-// NewJWTAuthenticator creates a new JWTAuthenticator instance.
-func NewJWTAuthenticator(audience, issuer string) Authenticator {
+// NewJWTAuthenticator creates a new JWTAuthenticator instance. When a revocation list is given,
+// tokens issued for a revoked session are rejected. It may be nil.
+func NewJWTAuthenticator(audience, issuer string, revocations *RevocationList) Authenticator {
 	return &JWTAuthenticator{
-		audience: audience,
-		issuer:   issuer,
+		audience:    audience,
+		issuer:      issuer,
+		revocations: revocations,
 	}
 }
 
-// GenerateToken generates a JWT token with the given claims and secret.
-func (a *JWTAuthenticator) GenerateToken(claims jwt.Claims, secret string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// GenerateToken generates a JWT token with the given claims, signed with the current signing key
+// of the provider. The token carries the ID of the key in its kid header.
+func (a *JWTAuthenticator) GenerateToken(claims jwt.Claims, keys KeyProvider) (string, error) {
+	key, err := keys.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	method, err := key.signingMethod()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
 
-	tokenStr, err := token.SignedString([]byte(secret))
+	tokenStr, err := token.SignedString(key.PrivateKey)
 	if err != nil {
 		return "", err
 	}
@@ -32,18 +52,41 @@ func (a *JWTAuthenticator) GenerateToken(claims jwt.Claims, secret string) (stri
 	return tokenStr, nil
 }
 
-// ValidateToken validates a JWT token with the given secret.
-func (a *JWTAuthenticator) ValidateToken(token, secret string) (*jwt.Token, error) {
-	return jwt.Parse(token, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+// ValidateToken validates a JWT token with the key named by its kid header. Any key the provider
+// still accepts can be used, so tokens signed before a key rotation remain valid until the
+// previous key is retired. Tokens issued for a session on the revocation list are rejected.
+func (a *JWTAuthenticator) ValidateToken(token string, keys KeyProvider) (*jwt.Token, error) {
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (any, error) {
+		keyID, _ := t.Header["kid"].(string)
+		key, err := keys.Key(keyID)
+		if err != nil {
+			return nil, err
+		}
+
+		// The algorithm is taken from the key rather than the token so that a token cannot
+		// be verified with a public key used as an HMAC secret.
+		if t.Method.Alg() != key.Algorithm {
 			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 		}
 
-		return []byte(secret), nil
+		return key.PublicKey, nil
 	},
 		jwt.WithExpirationRequired(),
 		jwt.WithAudience(a.audience),
 		jwt.WithIssuer(a.issuer),
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
+		jwt.WithValidMethods(keys.Algorithms()),
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.revocations != nil {
+		if claims, ok := parsed.Claims.(jwt.MapClaims); ok {
+			if sessionID, _ := claims[SessionIDClaim].(string); sessionID != "" && a.revocations.IsRevoked(sessionID) {
+				return nil, ErrTokenRevoked
+			}
+		}
+	}
+
+	return parsed, nil
 }
@@ -0,0 +1,358 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Statuses of a managed key. Keys move from staged to active to previous, and are retired by
+// removing them from the key manager.
+const (
+	// KeyStatusStaged marks a key that is published for verification but not yet used to sign,
+	// so that services caching the JWKS learn about it before the first token signed with it.
+	KeyStatusStaged = "staged"
+	// KeyStatusActive marks the key that new tokens are signed with.
+	KeyStatusActive = "active"
+	// KeyStatusPrevious marks a key that was replaced by a newer active key. It is still used
+	// for verification until the end of its overlap window.
+	KeyStatusPrevious = "previous"
+)
+
+var (
+	ErrKeyNotFound     = errors.New("key not found")
+	ErrKeyNotStaged    = errors.New("key is not staged")
+	ErrRetireActiveKey = errors.New("active key cannot be retired")
+	ErrKeyNotRotatable = errors.New("only asymmetric signing keys can be rotated")
+)
+
+// ManagedKey is a key held by a KeyManager together with its rotation state.
+type ManagedKey struct {
+	Key        *Key
+	Status     string
+	PromotedAt time.Time
+	// ExpiresAt is the end of the overlap window of a previous key. It is zero for
+	// staged and active keys.
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// acceptable reports whether tokens signed with the key are still accepted at the given time.
+func (k *ManagedKey) acceptable(now time.Time) bool {
+	return k.Status != KeyStatusPrevious || now.Before(k.ExpiresAt)
+}
+
+// KeyStore persists the keys of a KeyManager, so that every instance of a service signs and
+// verifies tokens with the same keys.
+type KeyStore interface {
+	LoadKeys(ctx context.Context) ([]ManagedKey, error)
+	SaveKey(ctx context.Context, key ManagedKey) error
+	DeleteKey(ctx context.Context, keyID string) error
+}
+
+// KeyManager rotates signing keys without invalidating issued tokens. It holds one active key
+// that tokens are signed with, staged keys waiting to be promoted and previous keys that are
+// only used for verification until their overlap window ends. Keys are retired by removing
+// them, after which tokens signed with them are rejected.
+type KeyManager struct {
+	store   KeyStore
+	overlap time.Duration
+
+	mu   sync.RWMutex
+	keys map[string]*ManagedKey
+	// staticKeys are verification keys from configuration that are not managed by rotation.
+	staticKeys map[string]*Key
+}
+
+// NewKeyManager creates a new KeyManager instance. Previous keys are accepted for the overlap
+// after they are replaced, which should be at least the lifetime of the tokens they signed.
+// Without a store, the keys are only held in memory.
+func NewKeyManager(store KeyStore, overlap time.Duration) *KeyManager {
+	return &KeyManager{
+		store:      store,
+		overlap:    overlap,
+		keys:       make(map[string]*ManagedKey),
+		staticKeys: make(map[string]*Key),
+	}
+}
+
+// Bootstrap loads the stored keys and adds the keys of the key set loaded from configuration.
+// The signing key of the set becomes the active key when no key is active yet, and is ignored
+// otherwise, since rotation has taken over from it. The other keys of the set are always
+// accepted for verification. It fails when there is no key to sign with.
+func (m *KeyManager) Bootstrap(ctx context.Context, keys *KeySet) error {
+	if err := m.Refresh(ctx); err != nil {
+		return err
+	}
+
+	signingKey, err := keys.SigningKey()
+	if err != nil && !errors.Is(err, ErrNoSigningKey) {
+		return err
+	}
+
+	m.mu.Lock()
+	for _, key := range keys.Keys() {
+		if key != signingKey {
+			m.staticKeys[key.ID] = key
+		}
+	}
+	m.mu.Unlock()
+
+	if _, err := m.SigningKey(); err == nil {
+		return nil
+	}
+	if signingKey == nil {
+		return ErrNoSigningKey
+	}
+
+	now := time.Now()
+
+	return m.save(ctx, &ManagedKey{
+		Key:        signingKey,
+		Status:     KeyStatusActive,
+		PromotedAt: now,
+		CreatedAt:  now,
+	})
+}
+
+// Refresh reloads the keys from the store, picking up rotations made by other instances.
+func (m *KeyManager) Refresh(ctx context.Context) error {
+	if m.store == nil {
+		return nil
+	}
+
+	storedKeys, err := m.store.LoadKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*ManagedKey, len(storedKeys))
+	for _, key := range storedKeys {
+		keys[key.Key.ID] = &key
+	}
+
+	m.mu.Lock()
+	m.keys = keys
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Run refreshes the keys at the given interval until the context is canceled.
+func (m *KeyManager) Run(ctx context.Context, logger *zerolog.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Refresh(ctx); err != nil {
+				logger.Error().Err(err).Msg("failed to refresh signing keys")
+			}
+		}
+	}
+}
+
+// Stage adds a new signing key that is published for verification but not yet used to sign.
+func (m *KeyManager) Stage(ctx context.Context, key *Key) (*ManagedKey, error) {
+	if key.Algorithm == AlgorithmHS256 || !key.CanSign() {
+		return nil, ErrKeyNotRotatable
+	}
+
+	staged := &ManagedKey{
+		Key:       key,
+		Status:    KeyStatusStaged,
+		CreatedAt: time.Now(),
+	}
+	if err := m.save(ctx, staged); err != nil {
+		return nil, err
+	}
+
+	return staged, nil
+}
+
+// Promote makes a staged key the active key. The key it replaces is kept for verification
+// until the end of the overlap window, so that tokens signed with it remain valid. Promoting
+// the active key again demotes any other key left active by an interrupted promotion.
+// The keys are reloaded first, since the key may have been staged by another instance.
+func (m *KeyManager) Promote(ctx context.Context, keyID string) error {
+	if err := m.Refresh(ctx); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	key, ok := m.keys[keyID]
+	var activeKeys []ManagedKey
+	for _, managedKey := range m.keys {
+		if managedKey.Status == KeyStatusActive && managedKey.Key.ID != keyID {
+			activeKeys = append(activeKeys, *managedKey)
+		}
+	}
+	m.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrKeyNotFound, keyID)
+	}
+	if key.Status != KeyStatusStaged && key.Status != KeyStatusActive {
+		return fmt.Errorf("%w: %q is %s", ErrKeyNotStaged, keyID, key.Status)
+	}
+
+	// The new key is activated before the old one is demoted. If demoting fails, the newest
+	// active key is still the one used to sign, and promoting it again finishes the rotation.
+	now := time.Now()
+	promoted := *key
+	promoted.Status = KeyStatusActive
+	promoted.PromotedAt = now
+	if err := m.save(ctx, &promoted); err != nil {
+		return err
+	}
+
+	for _, activeKey := range activeKeys {
+		activeKey.Status = KeyStatusPrevious
+		activeKey.ExpiresAt = now.Add(m.overlap)
+		if err := m.save(ctx, &activeKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Retire removes a staged or previous key, so that tokens signed with it are rejected even
+// before its overlap window ends. The active key cannot be retired. The keys are reloaded
+// first, so that a key promoted by another instance is not taken for a previous key.
+func (m *KeyManager) Retire(ctx context.Context, keyID string) error {
+	if err := m.Refresh(ctx); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	key, ok := m.keys[keyID]
+	m.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrKeyNotFound, keyID)
+	}
+	if key.Status == KeyStatusActive {
+		return ErrRetireActiveKey
+	}
+
+	if m.store != nil {
+		if err := m.store.DeleteKey(ctx, keyID); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	delete(m.keys, keyID)
+	m.mu.Unlock()
+
+	return nil
+}
+
+// ManagedKeys returns the keys of the manager with their rotation state, oldest first.
+func (m *KeyManager) ManagedKeys() []ManagedKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]ManagedKey, 0, len(m.keys))
+	for _, key := range m.keys {
+		keys = append(keys, *key)
+	}
+
+	slices.SortFunc(keys, func(a, b ManagedKey) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return keys
+}
+
+// SigningKey returns the active key. Should an interrupted promotion leave several keys
+// active, the most recently promoted one is used.
+func (m *KeyManager) SigningKey() (*Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var signingKey *ManagedKey
+	for _, key := range m.keys {
+		if key.Status != KeyStatusActive {
+			continue
+		}
+		if signingKey == nil || key.PromotedAt.After(signingKey.PromotedAt) {
+			signingKey = key
+		}
+	}
+
+	if signingKey == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	return signingKey.Key, nil
+}
+
+// Key returns the verification key with the given ID. Staged, active and previous keys whose
+// overlap window has not ended are accepted.
+func (m *KeyManager) Key(keyID string) (*Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if key, ok := m.keys[keyID]; ok && key.acceptable(time.Now()) {
+		return key.Key, nil
+	}
+	if key, ok := m.staticKeys[keyID]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, keyID)
+}
+
+// Keys returns every key that tokens are currently verified with.
+func (m *KeyManager) Keys() []*Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]*Key, 0, len(m.keys)+len(m.staticKeys))
+	for _, key := range m.keys {
+		if key.acceptable(now) {
+			keys = append(keys, key.Key)
+		}
+	}
+	for _, key := range m.staticKeys {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// Algorithms returns the signing algorithms used by the verification keys.
+func (m *KeyManager) Algorithms() []string {
+	return NewKeySet(m.Keys()...).Algorithms()
+}
+
+// JWKS returns the public keys that tokens are currently verified with.
+func (m *KeyManager) JWKS() (*JWKS, error) {
+	return NewJWKS(m.Keys())
+}
+
+// save persists the key and updates the copy held in memory.
+func (m *KeyManager) save(ctx context.Context, key *ManagedKey) error {
+	if m.store != nil {
+		if err := m.store.SaveKey(ctx, *key); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	m.keys[key.Key.ID] = key
+	m.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms supported for JWTs.
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrNoSigningKey         = errors.New("key set has no signing key")
+	ErrUnknownKeyID         = errors.New("unknown key ID")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrKeyAlgorithmMismatch = errors.New("key does not match signing algorithm")
+)
+
+// Key is a key used to sign or verify JWTs. HMAC keys use the same secret for both, while
+// asymmetric keys are verified with the public key and, on the service that issues tokens,
+// signed with the private key. Services that only verify tokens hold no private key.
+type Key struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+}
+
+// NewSecretKey creates an HS256 key from a shared secret. Secret keys have no ID, since
+// there is only ever one secret per kind of token.
+func NewSecretKey(secret string) *Key {
+	return &Key{
+		Algorithm:  AlgorithmHS256,
+		PrivateKey: []byte(secret),
+		PublicKey:  []byte(secret),
+	}
+}
+
+// NewPrivateKey creates an asymmetric signing key. The key ID is derived from the public key.
+func NewPrivateKey(algorithm string, privateKey crypto.Signer) (*Key, error) {
+	key, err := NewPublicKey(algorithm, privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	key.PrivateKey = privateKey
+
+	return key, nil
+}
+
+// NewPublicKey creates an asymmetric verification key. The key ID is derived from the public
+// key, so that it is the same on the service that signs tokens and the services that verify them.
+func NewPublicKey(algorithm string, publicKey crypto.PublicKey) (*Key, error) {
+	if err := checkKeyAlgorithm(algorithm, publicKey); err != nil {
+		return nil, err
+	}
+
+	keyID, err := KeyID(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		ID:        keyID,
+		Algorithm: algorithm,
+		PublicKey: publicKey,
+	}, nil
+}
+
+// GenerateKey generates a new asymmetric signing key for the algorithm.
+func GenerateKey(algorithm string) (*Key, error) {
+	var (
+		privateKey crypto.Signer
+		err        error
+	)
+	switch algorithm {
+	case AlgorithmRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgorithmES256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("%w: cannot generate %s keys", ErrUnsupportedAlgorithm, algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPrivateKey(algorithm, privateKey)
+}
+
+// KeyID returns the ID of a public key, the base64url encoded SHA-256 digest of its
+// DER encoded SubjectPublicKeyInfo.
+func KeyID(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.Sum256(der)
+
+	return base64.RawURLEncoding.EncodeToString(digest[:]), nil
+}
+
+// CanSign reports whether the key can sign tokens.
+func (k *Key) CanSign() bool {
+	return k.PrivateKey != nil
+}
+
+// signingMethod returns the JWT signing method of the key.
+func (k *Key) signingMethod() (jwt.SigningMethod, error) {
+	method := jwt.GetSigningMethod(k.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, k.Algorithm)
+	}
+
+	return method, nil
+}
+
+// KeySet holds the keys that tokens are verified with, selected by the kid header of the
+// token, and the key that new tokens are signed with.
+type KeySet struct {
+	signingKey *Key
+	keys       map[string]*Key
+}
+
+// NewKeySet creates a key set from the given keys. The first key that can sign becomes the
+// signing key, while every key is used for verification.
+func NewKeySet(keys ...*Key) *KeySet {
+	keySet := &KeySet{keys: make(map[string]*Key, len(keys))}
+	for _, key := range keys {
+		if keySet.signingKey == nil && key.CanSign() {
+			keySet.signingKey = key
+		}
+
+		keySet.keys[key.ID] = key
+	}
+
+	return keySet
+}
+
+// NewSecretKeySet creates a key set that signs and verifies tokens with a shared secret.
+func NewSecretKeySet(secret string) *KeySet {
+	return NewKeySet(NewSecretKey(secret))
+}
+
+// SigningKey returns the key that new tokens are signed with.
+func (s *KeySet) SigningKey() (*Key, error) {
+	if s.signingKey == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	return s.signingKey, nil
+}
+
+// Key returns the verification key with the given ID.
+func (s *KeySet) Key(keyID string) (*Key, error) {
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, keyID)
+	}
+
+	return key, nil
+}
+
+// Keys returns every verification key of the set.
+func (s *KeySet) Keys() []*Key {
+	keys := make([]*Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// Algorithms returns the signing algorithms used by the keys of the set.
+func (s *KeySet) Algorithms() []string {
+	seen := make(map[string]bool, len(s.keys))
+	algorithms := make([]string, 0, len(s.keys))
+	for _, key := range s.keys {
+		if !seen[key.Algorithm] {
+			seen[key.Algorithm] = true
+			algorithms = append(algorithms, key.Algorithm)
+		}
+	}
+
+	return algorithms
+}
+
+// checkKeyAlgorithm verifies that the public key can be used with the signing algorithm.
+func checkKeyAlgorithm(algorithm string, publicKey crypto.PublicKey) error {
+	switch algorithm {
+	case AlgorithmRS256:
+		if _, ok := publicKey.(*rsa.PublicKey); ok {
+			return nil
+		}
+	case AlgorithmES256:
+		if key, ok := publicKey.(*ecdsa.PublicKey); ok && key.Curve == elliptic.P256() {
+			return nil
+		}
+	case AlgorithmEdDSA:
+		if _, ok := publicKey.(ed25519.PublicKey); ok {
+			return nil
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	return fmt.Errorf("%w: %T cannot be used with %s", ErrKeyAlgorithmMismatch, publicKey, algorithm)
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	ErrInvalidPEM = errors.New("invalid PEM encoded key")
+	ErrNoKeys     = errors.New("key set has neither a secret nor any keys")
+)
+
+// KeySetConfig contains the configuration for loading a key set. With the default HS256
+// algorithm, tokens are signed and verified with a shared secret. With an asymmetric algorithm,
+// tokens are signed with the private key and verified with its public key and the additional
+// public keys, such as keys that were recently rotated out. Services that only verify tokens
+// set PublicKeyFiles without a PrivateKeyFile.
+type KeySetConfig struct {
+	Algorithm      string   `env:"ALGORITHM"        envDefault:"HS256"`
+	Secret         string   `env:"SECRET"`
+	PrivateKeyFile string   `env:"PRIVATE_KEY_FILE"`
+	PublicKeyFiles []string `env:"PUBLIC_KEY_FILES"`
+}
+
+// LoadKeySet creates a key set from the configuration, reading the PEM encoded keys from disk.
+func LoadKeySet(cfg KeySetConfig) (*KeySet, error) {
+	if cfg.Algorithm == AlgorithmHS256 {
+		if cfg.Secret == "" {
+			return nil, ErrNoKeys
+		}
+
+		return NewSecretKeySet(cfg.Secret), nil
+	}
+
+	keys := make([]*Key, 0, len(cfg.PublicKeyFiles)+1)
+	if cfg.PrivateKeyFile != "" {
+		privateKey, err := ReadPrivateKeyFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := NewPrivateKey(cfg.Algorithm, privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.PrivateKeyFile, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	for _, path := range cfg.PublicKeyFiles {
+		publicKey, err := ReadPublicKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := NewPublicKey(cfg.Algorithm, publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	return NewKeySet(keys...), nil
+}
+
+// ReadPrivateKeyFile reads a PEM encoded private key from a file.
+func ReadPrivateKeyFile(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return privateKey, nil
+}
+
+// ReadPublicKeyFile reads a PEM encoded public key from a file.
+func ReadPublicKeyFile(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := ParsePublicKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return publicKey, nil
+}
+
+// MarshalPrivateKeyPEM encodes a private key as a PEM encoded PKCS #8 private key.
+func MarshalPrivateKeyPEM(privateKey crypto.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// ParsePrivateKeyPEM parses a PKCS #8, PKCS #1 RSA or SEC 1 EC private key.
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+
+	var (
+		privateKey any
+		err        error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: unexpected block type %q", ErrInvalidPEM, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPEM, err)
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported private key type %T", ErrInvalidPEM, privateKey)
+	}
+
+	return signer, nil
+}
+
+// ParsePublicKeyPEM parses a PKIX or PKCS #1 RSA public key, or the public key of a certificate.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+
+	var (
+		publicKey any
+		err       error
+	)
+	switch block.Type {
+	case "PUBLIC KEY":
+		publicKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		publicKey, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var certificate *x509.Certificate
+		if certificate, err = x509.ParseCertificate(block.Bytes); err == nil {
+			publicKey = certificate.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("%w: unexpected block type %q", ErrInvalidPEM, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPEM, err)
+	}
+
+	return publicKey, nil
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// SessionIDClaim is the claim that names the session a token was issued for.
+const SessionIDClaim = "session_id"
+
+// revocationSyncLookback is how far before the latest known revocation each sync starts, so
+// that revocations recorded slightly out of order by different instances are not missed.
+const revocationSyncLookback = time.Minute
+
+// Revocation is a session whose tokens are rejected before they expire. It only needs to be
+// kept until ExpiresAt, when the last token issued for the session has expired anyway.
+type Revocation struct {
+	SessionID string
+	ExpiresAt time.Time
+	RevokedAt time.Time
+}
+
+// RevocationSource provides the revocations recorded since a point in time. Revocations that
+// have already expired may be left out.
+type RevocationSource interface {
+	ListRevocations(ctx context.Context, since time.Time) ([]Revocation, error)
+}
+
+// RevocationList holds the revoked sessions in memory, so that tokens of revoked sessions are
+// rejected without a lookup per token. It is kept in sync by polling a revocation source, so
+// that every replica rejects a revoked session within one poll interval.
+type RevocationList struct {
+	source RevocationSource
+
+	mu       sync.RWMutex
+	sessions map[string]time.Time
+	syncedAt time.Time
+}
+
+// NewRevocationList creates a new RevocationList instance that syncs from the given source.
+func NewRevocationList(source RevocationSource) *RevocationList {
+	return &RevocationList{
+		source:   source,
+		sessions: make(map[string]time.Time),
+	}
+}
+
+// Add adds revocations to the list, taking effect immediately on this replica.
+func (l *RevocationList) Add(revocations ...Revocation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, revocation := range revocations {
+		if expiresAt, ok := l.sessions[revocation.SessionID]; !ok || revocation.ExpiresAt.After(expiresAt) {
+			l.sessions[revocation.SessionID] = revocation.ExpiresAt
+		}
+		if revocation.RevokedAt.After(l.syncedAt) {
+			l.syncedAt = revocation.RevokedAt
+		}
+	}
+}
+
+// IsRevoked reports whether tokens issued for the session are revoked.
+func (l *RevocationList) IsRevoked(sessionID string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	expiresAt, ok := l.sessions[sessionID]
+
+	return ok && time.Now().Before(expiresAt)
+}
+
+// Sync fetches the revocations recorded since the last sync and drops the revocations that
+// have expired.
+func (l *RevocationList) Sync(ctx context.Context) error {
+	l.mu.RLock()
+	since := l.syncedAt
+	l.mu.RUnlock()
+
+	if !since.IsZero() {
+		since = since.Add(-revocationSyncLookback)
+	}
+
+	revocations, err := l.source.ListRevocations(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	l.Add(revocations...)
+
+	now := time.Now()
+
+	l.mu.Lock()
+	for sessionID, expiresAt := range l.sessions {
+		if !now.Before(expiresAt) {
+			delete(l.sessions, sessionID)
+		}
+	}
+	l.mu.Unlock()
+
+	return nil
+}
+
+// Run syncs the list at the given interval until the context is canceled.
+func (l *RevocationList) Run(ctx context.Context, logger *zerolog.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Sync(ctx); err != nil {
+				logger.Error().Err(err).Msg("failed to sync session revocations")
+			}
+		}
+	}
+}
@@ -32,6 +32,9 @@ const (
 	ErrorCodeBadRequest   = "BAD_REQUEST"
 	ErrorCodeConflict     = "CONFLICT"
 	ErrorCodeRateLimit    = "RATE_LIMIT_EXCEEDED"
+
+	ErrorCodeRefreshTokenReused = "REFRESH_TOKEN_REUSED"
+	ErrorCodeEmailNotVerified   = "EMAIL_NOT_VERIFIED"
 )
 
 // NewSuccessResponse creates a new success response with the given data.
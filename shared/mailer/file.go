@@ -0,0 +1,87 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/rs/zerolog"
+)
+
+// FileMailer represents a development mailer that writes every message as an .eml file
+// into an outbox directory instead of delivering it.
+type FileMailer struct {
+	config *fileMailerConfig
+	from   string
+	logger *zerolog.Logger
+}
+
+// NewFileMailer creates a new FileMailer instance.
+func NewFileMailer(logger *zerolog.Logger, from string) *FileMailer {
+	cfg := newFileMailerConfig(logger)
+
+	if err := cfg.validate(); err != nil {
+		logger.Fatal().Err(err).Msg("failed to validate file mailer configuration")
+	}
+
+	if err := os.MkdirAll(cfg.OutboxDir, 0o750); err != nil {
+		logger.Fatal().Err(err).Msg("failed to create mailer outbox directory")
+	}
+
+	return &FileMailer{
+		config: cfg,
+		from:   from,
+		logger: logger,
+	}
+}
+
+// Send writes the message to the outbox directory.
+func (m *FileMailer) Send(_ context.Context, message *Message) error {
+	if err := message.validate(); err != nil {
+		return err
+	}
+
+	body, err := message.build(m.from)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), randomID()[:8])
+	path := filepath.Join(m.config.OutboxDir, name)
+
+	if err := os.WriteFile(path, body, 0o600); err != nil {
+		return err
+	}
+
+	m.logger.Info().Str("path", path).Strs("to", message.To).Msg("Wrote email to outbox")
+
+	return nil
+}
+
+// fileMailerConfig contains the configuration for the file mailer.
+type fileMailerConfig struct {
+	OutboxDir string `env:"MAILER_OUTBOX_DIR"`
+}
+
+// newFileMailerConfig creates a new fileMailerConfig instance from environment variables.
+func newFileMailerConfig(logger *zerolog.Logger) *fileMailerConfig {
+	cfg, err := env.ParseAs[fileMailerConfig]()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to parse environment variables")
+	}
+
+	return &cfg
+}
+
+// validate checks if the file mailer configuration is valid.
+func (c *fileMailerConfig) validate() error {
+	if c.OutboxDir == "" {
+		return errors.New("missing MAILER_OUTBOX_DIR environment variable")
+	}
+
+	return nil
+}
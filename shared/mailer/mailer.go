@@ -0,0 +1,169 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/rs/zerolog"
+)
+
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+// Mailer defines the interface for sending emails.
+type Mailer interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// Message represents an email message. At least one of TextBody or HTMLBody must be set.
+type Message struct {
+	To       []string
+	Subject  string
+	TextBody string
+	HTMLBody string
+}
+
+// New creates a new Mailer using the backend selected by the MAILER_DRIVER environment variable.
+func New(logger *zerolog.Logger) Mailer {
+	cfg := newMailerConfig(logger)
+
+	if err := cfg.validate(); err != nil {
+		logger.Fatal().Err(err).Msg("failed to validate mailer configuration")
+	}
+
+	switch cfg.Driver {
+	case DriverFile:
+		return NewFileMailer(logger, cfg.From)
+	case DriverMemory:
+		return NewMemoryMailer()
+	default:
+		return NewSMTPMailer(logger, cfg.From)
+	}
+}
+
+// validate checks if the message can be sent.
+func (m *Message) validate() error {
+	if len(m.To) == 0 {
+		return errors.New("message has no recipients")
+	}
+
+	if m.TextBody == "" && m.HTMLBody == "" {
+		return errors.New("message has no body")
+	}
+
+	return nil
+}
+
+// build renders the message in RFC 5322 format.
+func (m *Message) build(from string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", randomID(), domainOf(from))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	switch {
+	case m.TextBody != "" && m.HTMLBody != "":
+		boundary := randomID()
+		fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		if err := writePart(&buf, "text/plain", m.TextBody); err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&buf, "\r\n--%s\r\n", boundary)
+		if err := writePart(&buf, "text/html", m.HTMLBody); err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&buf, "\r\n--%s--\r\n", boundary)
+	case m.HTMLBody != "":
+		if err := writePart(&buf, "text/html", m.HTMLBody); err != nil {
+			return nil, err
+		}
+	default:
+		if err := writePart(&buf, "text/plain", m.TextBody); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writePart writes a quoted-printable encoded body part with its headers.
+func writePart(buf *bytes.Buffer, contentType, body string) error {
+	fmt.Fprintf(buf, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	writer := quotedprintable.NewWriter(buf)
+	if _, err := writer.Write([]byte(body)); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// randomID generates a random identifier used for message IDs and MIME boundaries.
+func randomID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
+
+// domainOf returns the domain part of an email address.
+func domainOf(address string) string {
+	address = strings.TrimSuffix(address, ">")
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return address[i+1:]
+	}
+
+	return "localhost"
+}
+
+// mailerConfig contains the configuration shared by every mailer backend.
+type mailerConfig struct {
+	Driver string `env:"MAILER_DRIVER" envDefault:"smtp"`
+	From   string `env:"MAILER_FROM"`
+}
+
+// newMailerConfig creates a new mailerConfig instance from environment variables.
+func newMailerConfig(logger *zerolog.Logger) *mailerConfig {
+	cfg, err := env.ParseAs[mailerConfig]()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to parse environment variables")
+	}
+
+	return &cfg
+}
+
+// validate checks if the mailer configuration is valid.
+func (c *mailerConfig) validate() error {
+	switch c.Driver {
+	case DriverSMTP, DriverFile, DriverMemory:
+	default:
+		return fmt.Errorf("unsupported MAILER_DRIVER: %s", c.Driver)
+	}
+
+	if c.From == "" {
+		return errors.New("missing MAILER_FROM environment variable")
+	}
+
+	return nil
+}
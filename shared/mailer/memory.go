@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryMailer represents a mailer that captures messages in memory, for use in tests.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryMailer creates a new MemoryMailer instance.
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// Send captures the message.
+func (m *MemoryMailer) Send(_ context.Context, message *Message) error {
+	if err := message.validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	captured := *message
+	captured.To = append([]string(nil), message.To...)
+	m.messages = append(m.messages, captured)
+
+	return nil
+}
+
+// Messages returns a copy of every message captured so far.
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
+
+// Reset discards every captured message.
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = nil
+}
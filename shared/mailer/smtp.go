@@ -0,0 +1,138 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/rs/zerolog"
+)
+
+// SMTPMailer represents a mailer that delivers messages through an SMTP server.
+type SMTPMailer struct {
+	config *smtpConfig
+	from   string
+}
+
+// NewSMTPMailer creates a new SMTPMailer instance.
+func NewSMTPMailer(logger *zerolog.Logger, from string) *SMTPMailer {
+	cfg := newSMTPConfig(logger)
+
+	if err := cfg.validate(); err != nil {
+		logger.Fatal().Err(err).Msg("failed to validate SMTP configuration")
+	}
+
+	return &SMTPMailer{
+		config: cfg,
+		from:   from,
+	}
+}
+
+// Send delivers the message through the configured SMTP server.
+func (m *SMTPMailer) Send(ctx context.Context, message *Message) error {
+	if err := message.validate(); err != nil {
+		return err
+	}
+
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return err
+	}
+
+	body, err := message.build(m.from)
+	if err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.config.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+
+	if m.config.Username != "" {
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(sender.Address); err != nil {
+		return err
+	}
+
+	for _, recipient := range message.To {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(body); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// smtpConfig contains SMTP server connection configuration.
+type smtpConfig struct {
+	Host     string `env:"SMTP_HOST"`
+	Port     int    `env:"SMTP_PORT" envDefault:"587"`
+	Username string `env:"SMTP_USERNAME"`
+	Password string `env:"SMTP_PASSWORD"`
+}
+
+// newSMTPConfig creates a new smtpConfig instance from environment variables.
+func newSMTPConfig(logger *zerolog.Logger) *smtpConfig {
+	cfg, err := env.ParseAs[smtpConfig]()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to parse environment variables")
+	}
+
+	return &cfg
+}
+
+// validate checks if the SMTP configuration is valid.
+func (c *smtpConfig) validate() error {
+	if c.Host == "" {
+		return errors.New("missing SMTP_HOST environment variable")
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/rs/zerolog"
+)
+
+var ErrUnknownProvider = errors.New("unknown OAuth provider")
+
+// Provider defines the interface for an external identity provider that signs users in
+// with the OAuth 2.0 authorization code flow.
+type Provider interface {
+	// Name returns the name the provider is registered under, such as "google".
+	Name() string
+
+	// AuthCodeURL returns the URL the user is sent to in order to sign in with the provider.
+	// The nonce is bound to the ID token and the code challenge is the S256 PKCE challenge
+	// for the verifier later passed to Exchange.
+	AuthCodeURL(state, nonce, codeChallenge string) string
+
+	// Exchange redeems the authorization code and returns the verified identity of the user.
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*UserInfo, error)
+}
+
+// UserInfo represents the identity of a user asserted by a provider.
+type UserInfo struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Registry holds the configured providers by name.
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry creates a new Registry containing the given providers.
+func NewRegistry(providers ...Provider) *Registry {
+	registry := &Registry{providers: make(map[string]Provider, len(providers))}
+	for _, provider := range providers {
+		registry.providers[provider.Name()] = provider
+	}
+
+	return registry
+}
+
+// Provider returns the provider registered under the name.
+func (r *Registry) Provider(name string) (Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+
+	return provider, nil
+}
+
+// oauthConfig contains the names of the enabled providers.
+type oauthConfig struct {
+	Providers []string `env:"OAUTH_PROVIDERS"`
+}
+
+// New creates a new Registry with an OIDC provider for every name listed in the
+// OAUTH_PROVIDERS environment variable. Each provider is configured through environment
+// variables prefixed with its name, such as OAUTH_GOOGLE_CLIENT_ID.
+func New(ctx context.Context, logger *zerolog.Logger) *Registry {
+	cfg, err := env.ParseAs[oauthConfig]()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to parse environment variables")
+	}
+
+	providers := make([]Provider, 0, len(cfg.Providers))
+	for _, name := range cfg.Providers {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		providerCfg, err := env.ParseAsWithOptions[OIDCConfig](env.Options{
+			Prefix: "OAUTH_" + strings.ToUpper(name) + "_",
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Str("provider", name).Msg("failed to parse environment variables")
+		}
+
+		provider, err := NewOIDCProvider(ctx, name, providerCfg)
+		if err != nil {
+			logger.Fatal().Err(err).Str("provider", name).Msg("failed to create OIDC provider")
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return NewRegistry(providers...)
+}
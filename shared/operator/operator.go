@@ -0,0 +1,73 @@
+package operator
+
+import (
+	"context"
+	"crypto/subtle"
+	"path"
+	"slices"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metadataKeyOperatorToken is the gRPC metadata key that carries the operator token.
+const metadataKeyOperatorToken = "x-operator-token"
+
+// WithToken attaches the operator token to the outgoing gRPC metadata of the context.
+func WithToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, metadataKeyOperatorToken, token)
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor that only lets callers that present the
+// operator token call the RPCs named in methods, such as "UnlockAccount". Those RPCs are
+// refused to everyone when no token is configured.
+func UnaryServerInterceptor(
+	logger *zerolog.Logger,
+	token string,
+	methods []string,
+) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		method := path.Base(info.FullMethod)
+
+		if !slices.Contains(methods, method) {
+			return handler(ctx, req)
+		}
+
+		if token == "" {
+			return nil, status.Errorf(codes.PermissionDenied, "operator access is disabled")
+		}
+
+		presented := tokenFromContext(ctx)
+		if presented == "" {
+			return nil, status.Errorf(codes.Unauthenticated, "operator token required")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			logger.Warn().Str("method", method).Msg("rejected invalid operator token")
+			return nil, status.Errorf(codes.PermissionDenied, "invalid operator token")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func tokenFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if values := md.Get(metadataKeyOperatorToken); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
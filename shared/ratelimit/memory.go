@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store evicts buckets that have refilled completely.
+const sweepInterval = time.Minute
+
+// MemoryStore represents a token bucket store that keeps buckets in process memory.
+// Limits are enforced per process, so it only suits single-replica deployments.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+// NewMemoryStore creates a new MemoryStore instance.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take removes a token from each bucket identified by keys, refilling them first for the
+// time elapsed since they were last used. No token is removed unless every bucket has one.
+func (s *MemoryStore) Take(_ context.Context, keys []string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	capacity := float64(limit.Requests)
+	refillInterval := limit.refillInterval()
+
+	buckets := make([]*bucket, 0, len(keys))
+	var retryAfter time.Duration
+	for _, key := range keys {
+		b, ok := s.buckets[key]
+		if !ok {
+			b = &bucket{tokens: capacity, updatedAt: now}
+			s.buckets[key] = b
+		}
+
+		elapsed := now.Sub(b.updatedAt)
+		b.tokens = min(capacity, b.tokens+float64(elapsed)/float64(refillInterval))
+		b.updatedAt = now
+		b.fullAt = now.Add(time.Duration((capacity - b.tokens) * float64(refillInterval)))
+
+		if b.tokens < 1 {
+			retryAfter = max(retryAfter, time.Duration((1-b.tokens)*float64(refillInterval)))
+		}
+
+		buckets = append(buckets, b)
+	}
+
+	if retryAfter > 0 {
+		return Result{Allowed: false, RetryAfter: retryAfter}, nil
+	}
+
+	remaining := limit.Requests
+	for _, b := range buckets {
+		b.tokens--
+		b.fullAt = now.Add(time.Duration((capacity - b.tokens) * float64(refillInterval)))
+		remaining = min(remaining, int(b.tokens))
+	}
+
+	return Result{Allowed: true, Remaining: remaining}, nil
+}
+
+// sweep evicts buckets that are full again, since they are indistinguishable from new ones.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.lastSweep = now
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit describes a token bucket that holds up to Requests tokens and refills
+// completely over Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Result represents the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Store defines the interface for token bucket storage. Implementations backed by a
+// shared database make the limits apply across every replica of a service.
+type Store interface {
+	// Take removes a token from every bucket identified by keys, but only when each of them
+	// has one, so that a request denied by one bucket does not use up the others.
+	Take(ctx context.Context, keys []string, limit Limit) (Result, error)
+}
+
+// ParseLimit parses a limit in the "<requests>/<period>" format, such as "10/1m".
+func ParseLimit(value string) (Limit, error) {
+	requests, period, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<period>", value)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", value)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", value)
+	}
+
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// ParseLimits parses a set of limits keyed by name using ParseLimit.
+func ParseLimits(values map[string]string) (map[string]Limit, error) {
+	limits := make(map[string]Limit, len(values))
+	for name, value := range values {
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		limits[name] = limit
+	}
+
+	return limits, nil
+}
+
+// refillInterval returns how long it takes for a single token to be added back to the bucket.
+func (l Limit) refillInterval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
@@ -0,0 +1,203 @@
+package security
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/rs/zerolog"
+)
+
+// bloomFilterMagic identifies files written by BloomFilter.WriteTo.
+const bloomFilterMagic = "OPBF\x01"
+
+// BloomFilter represents a compact, probabilistic set of SHA-1 digests. It never reports
+// a false negative, while false positives happen at the rate chosen when it was created.
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint32
+}
+
+// NewBloomFilter creates a new BloomFilter sized for the expected number of entries
+// and the given false positive rate.
+func NewBloomFilter(entries uint64, falsePositiveRate float64) *BloomFilter {
+	n := float64(max(entries, 1))
+	size := uint64(math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := uint32(max(1, math.Round(float64(size)/n*math.Ln2)))
+
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// ReadBloomFilter reads a BloomFilter previously written with WriteTo.
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(bloomFilterMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != bloomFilterMagic {
+		return nil, errors.New("not a bloom filter file")
+	}
+
+	var header struct {
+		Size   uint64
+		Hashes uint32
+	}
+	if err := binary.Read(br, binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+
+	if header.Size == 0 || header.Hashes == 0 {
+		return nil, errors.New("invalid bloom filter header")
+	}
+
+	words := header.Size / 64
+	if header.Size%64 != 0 {
+		words++
+	}
+	if words > math.MaxInt64/8-1 {
+		return nil, fmt.Errorf("bloom filter size %d is too large", header.Size)
+	}
+
+	// The bits are read without allocating for the size in the header up front, so that a
+	// corrupt header cannot exhaust memory, and they must take up exactly the rest of the file.
+	data, err := io.ReadAll(io.LimitReader(br, int64(words)*8+1))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(data)) != words*8 {
+		return nil, fmt.Errorf("bloom filter size %d does not match the file length", header.Size)
+	}
+
+	filter := &BloomFilter{
+		bits:   make([]uint64, words),
+		size:   header.Size,
+		hashes: header.Hashes,
+	}
+	for i := range filter.bits {
+		filter.bits[i] = binary.BigEndian.Uint64(data[i*8:])
+	}
+
+	return filter, nil
+}
+
+// Add adds a SHA-1 digest to the filter.
+func (f *BloomFilter) Add(digest [sha1.Size]byte) {
+	h1, h2 := splitDigest(digest)
+	for i := range uint64(f.hashes) {
+		bit := (h1 + i*h2) % f.size
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains reports whether the SHA-1 digest may have been added to the filter.
+func (f *BloomFilter) Contains(digest [sha1.Size]byte) bool {
+	h1, h2 := splitDigest(digest)
+	for i := range uint64(f.hashes) {
+		bit := (h1 + i*h2) % f.size
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WriteTo writes the filter in a binary format that can be read with ReadBloomFilter.
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+
+	if _, err := bw.WriteString(bloomFilterMagic); err != nil {
+		return 0, err
+	}
+	if err := binary.Write(bw, binary.BigEndian, f.size); err != nil {
+		return 0, err
+	}
+	if err := binary.Write(bw, binary.BigEndian, f.hashes); err != nil {
+		return 0, err
+	}
+	if err := binary.Write(bw, binary.BigEndian, f.bits); err != nil {
+		return 0, err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+
+	return int64(len(bloomFilterMagic)) + 8 + 4 + int64(len(f.bits))*8, nil
+}
+
+// splitDigest derives the two hashes used for double hashing from a SHA-1 digest, which
+// is already uniformly distributed.
+func splitDigest(digest [sha1.Size]byte) (uint64, uint64) {
+	return binary.BigEndian.Uint64(digest[0:8]), binary.BigEndian.Uint64(digest[8:16]) | 1
+}
+
+// BreachChecker checks passwords against a bloom filter of known breached password hashes.
+// A checker without a filter treats every password as safe.
+type BreachChecker struct {
+	filter *BloomFilter
+}
+
+// breachCheckerConfig contains the configuration for the breached password check.
+type breachCheckerConfig struct {
+	FilterFile string `env:"BREACHED_PASSWORDS_FILE"`
+}
+
+// NewBreachChecker creates a new BreachChecker from the bloom filter file named by the
+// BREACHED_PASSWORDS_FILE environment variable. The check is disabled when it is not set.
+func NewBreachChecker(logger *zerolog.Logger) *BreachChecker {
+	cfg, err := env.ParseAs[breachCheckerConfig]()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to parse environment variables")
+	}
+
+	if cfg.FilterFile == "" {
+		logger.Warn().Msg("BREACHED_PASSWORDS_FILE is not set, breached password check is disabled")
+		return &BreachChecker{}
+	}
+
+	checker, err := LoadBreachChecker(cfg.FilterFile)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to load breached password filter")
+	}
+
+	return checker
+}
+
+// LoadBreachChecker creates a new BreachChecker from a bloom filter file.
+func LoadBreachChecker(path string) (*BreachChecker, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	filter, err := ReadBloomFilter(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return &BreachChecker{filter: filter}, nil
+}
+
+// IsCompromised reports whether the password appears in the breach corpus the filter was built from.
+func (c *BreachChecker) IsCompromised(password string) bool {
+	if c.filter == nil {
+		return false
+	}
+
+	return c.filter.Contains(sha1.Sum([]byte(password)))
+}
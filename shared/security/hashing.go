@@ -1,9 +1,53 @@
 package security
 
-import "github.com/matthewhartstonge/argon2"
+import (
+	"github.com/caarlos0/env/v11"
+	"github.com/matthewhartstonge/argon2"
+	"github.com/rs/zerolog"
+)
 
-func HashPassword(password string) (string, error) {
-	argon := argon2.DefaultConfig()
+// VerifyPassword verifies a password against an encoded hash, using the algorithm detected
+// with HashAlgorithm.
+func VerifyPassword(password, encodedHash string) (bool, error) {
+	algorithm, err := HashAlgorithm(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	switch algorithm {
+	case HashAlgorithmBcrypt:
+		return verifyBcrypt(password, encodedHash)
+	case HashAlgorithmPBKDF2:
+		return verifyPBKDF2(password, encodedHash)
+	case HashAlgorithmScrypt:
+		return verifyScrypt(password, encodedHash)
+	default:
+		return argon2.VerifyEncoded([]byte(password), []byte(encodedHash))
+	}
+}
+
+// PasswordHasher hashes passwords with argon2 using configurable cost parameters. Hashes
+// embed the parameters they were created with, so they can be verified after the
+// parameters change and upgraded when NeedsRehash reports them as outdated.
+type PasswordHasher struct {
+	MemoryCost  uint32 `env:"ARGON2_MEMORY_COST" envDefault:"65536"`
+	TimeCost    uint32 `env:"ARGON2_TIME_COST"   envDefault:"3"`
+	Parallelism uint8  `env:"ARGON2_PARALLELISM" envDefault:"4"`
+}
+
+// NewPasswordHasher creates a new PasswordHasher instance from environment variables.
+func NewPasswordHasher(logger *zerolog.Logger) *PasswordHasher {
+	hasher, err := env.ParseAs[PasswordHasher]()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to parse environment variables")
+	}
+
+	return &hasher
+}
+
+// Hash hashes the password with the configured parameters.
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	argon := h.config()
 	encoded, err := argon.HashEncoded([]byte(password))
 	if err != nil {
 		return "", err
@@ -12,6 +56,36 @@ func HashPassword(password string) (string, error) {
 	return string(encoded), nil
 }
 
-func VerifyPassword(password, encodedHash string) (bool, error) {
-	return argon2.VerifyEncoded([]byte(password), []byte(encodedHash))
+// Verify reports whether the password matches the encoded hash, whatever algorithm and
+// parameters it was created with.
+func (h *PasswordHasher) Verify(password, encodedHash string) (bool, error) {
+	return VerifyPassword(password, encodedHash)
+}
+
+// NeedsRehash reports whether the encoded hash was created with an algorithm or parameters
+// other than the configured ones.
+func (h *PasswordHasher) NeedsRehash(encodedHash string) bool {
+	raw, err := argon2.Decode([]byte(encodedHash))
+	if err != nil {
+		return true
+	}
+
+	want := h.config()
+
+	return raw.Config.Mode != want.Mode ||
+		raw.Config.Version != want.Version ||
+		raw.Config.MemoryCost != want.MemoryCost ||
+		raw.Config.TimeCost != want.TimeCost ||
+		raw.Config.Parallelism != want.Parallelism ||
+		raw.Config.HashLength != want.HashLength
+}
+
+// config returns the argon2 configuration for the configured parameters.
+func (h *PasswordHasher) config() argon2.Config {
+	argon := argon2.DefaultConfig()
+	argon.MemoryCost = h.MemoryCost
+	argon.TimeCost = h.TimeCost
+	argon.Parallelism = h.Parallelism
+
+	return argon
 }
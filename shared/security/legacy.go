@@ -0,0 +1,180 @@
+package security
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	HashAlgorithmArgon2 = "argon2"
+	HashAlgorithmBcrypt = "bcrypt"
+	HashAlgorithmPBKDF2 = "pbkdf2"
+	HashAlgorithmScrypt = "scrypt"
+)
+
+var ErrUnsupportedHash = errors.New("unsupported password hash format")
+
+// passLibBase64 is the base64 variant used by passlib, which replaces "+" with ".".
+var passLibBase64 = base64.NewEncoding(
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789./",
+).WithPadding(base64.NoPadding)
+
+// HashAlgorithm detects the algorithm of an encoded password hash from its prefix. Besides
+// argon2, it recognizes the bcrypt, PBKDF2 and scrypt formats used by passlib and the
+// PBKDF2 format used by Django, so that users imported from other systems can sign in.
+func HashAlgorithm(encodedHash string) (string, error) {
+	switch {
+	case strings.HasPrefix(encodedHash, "$argon2"):
+		return HashAlgorithmArgon2, nil
+	case strings.HasPrefix(encodedHash, "$2a$"),
+		strings.HasPrefix(encodedHash, "$2b$"),
+		strings.HasPrefix(encodedHash, "$2y$"):
+		return HashAlgorithmBcrypt, nil
+	case strings.HasPrefix(encodedHash, "$pbkdf2"), strings.HasPrefix(encodedHash, "pbkdf2_"):
+		return HashAlgorithmPBKDF2, nil
+	case strings.HasPrefix(encodedHash, "$scrypt$"):
+		return HashAlgorithmScrypt, nil
+	default:
+		return "", ErrUnsupportedHash
+	}
+}
+
+// verifyBcrypt verifies a password against a bcrypt hash.
+func verifyBcrypt(password, encodedHash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// verifyPBKDF2 verifies a password against a PBKDF2 hash in either the passlib format
+// "$pbkdf2-<digest>$<iterations>$<salt>$<hash>" or the Django format
+// "pbkdf2_<digest>$<iterations>$<salt>$<hash>".
+func verifyPBKDF2(password, encodedHash string) (bool, error) {
+	passLib := strings.HasPrefix(encodedHash, "$")
+
+	parts := strings.Split(strings.TrimPrefix(encodedHash, "$"), "$")
+	if len(parts) != 4 {
+		return false, fmt.Errorf("%w: malformed PBKDF2 hash", ErrUnsupportedHash)
+	}
+
+	var digest string
+	if passLib {
+		digest = strings.TrimPrefix(strings.TrimPrefix(parts[0], "pbkdf2"), "-")
+	} else {
+		digest = strings.TrimPrefix(parts[0], "pbkdf2_")
+	}
+
+	newHash, err := pbkdf2Digest(digest)
+	if err != nil {
+		return false, err
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false, fmt.Errorf("%w: malformed PBKDF2 iterations", ErrUnsupportedHash)
+	}
+
+	// passlib encodes the salt and the hash with its own base64 variant, while Django
+	// uses the salt as is and encodes the hash with standard base64.
+	salt := []byte(parts[2])
+	var expected []byte
+	if passLib {
+		if salt, err = passLibBase64.DecodeString(parts[2]); err != nil {
+			return false, fmt.Errorf("%w: malformed PBKDF2 salt", ErrUnsupportedHash)
+		}
+		expected, err = passLibBase64.DecodeString(parts[3])
+	} else {
+		expected, err = base64.StdEncoding.DecodeString(parts[3])
+	}
+	if err != nil || len(expected) == 0 {
+		return false, fmt.Errorf("%w: malformed PBKDF2 hash", ErrUnsupportedHash)
+	}
+
+	actual, err := pbkdf2.Key(newHash, password, salt, iterations, len(expected))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(actual, expected) == 1, nil
+}
+
+// pbkdf2Digest returns the hash function named in a PBKDF2 hash, where an empty name means SHA-1.
+func pbkdf2Digest(name string) (func() hash.Hash, error) {
+	switch name {
+	case "", "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported PBKDF2 digest %q", ErrUnsupportedHash, name)
+	}
+}
+
+// verifyScrypt verifies a password against a scrypt hash in the passlib format
+// "$scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<hash>".
+func verifyScrypt(password, encodedHash string) (bool, error) {
+	parts := strings.Split(strings.TrimPrefix(encodedHash, "$"), "$")
+	if len(parts) != 4 {
+		return false, fmt.Errorf("%w: malformed scrypt hash", ErrUnsupportedHash)
+	}
+
+	var logN, r, p int
+	for param := range strings.SplitSeq(parts[1], ",") {
+		key, value, _ := strings.Cut(param, "=")
+
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return false, fmt.Errorf("%w: malformed scrypt parameter %q", ErrUnsupportedHash, param)
+		}
+
+		switch key {
+		case "ln":
+			logN = n
+		case "r":
+			r = n
+		case "p":
+			p = n
+		}
+	}
+
+	if logN <= 0 || logN >= 32 || r <= 0 || p <= 0 {
+		return false, fmt.Errorf("%w: malformed scrypt parameters", ErrUnsupportedHash)
+	}
+
+	salt, err := passLibBase64.DecodeString(parts[2])
+	if err != nil {
+		return false, fmt.Errorf("%w: malformed scrypt salt", ErrUnsupportedHash)
+	}
+
+	expected, err := passLibBase64.DecodeString(parts[3])
+	if err != nil || len(expected) == 0 {
+		return false, fmt.Errorf("%w: malformed scrypt hash", ErrUnsupportedHash)
+	}
+
+	actual, err := scrypt.Key([]byte(password), salt, 1<<logN, r, p, len(expected))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(actual, expected) == 1, nil
+}
@@ -0,0 +1,179 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/rs/zerolog"
+)
+
+// minPersonalInfoLength is the shortest piece of personal information a password is checked against,
+// so that short names do not rule out most passwords.
+const minPersonalInfoLength = 3
+
+var ErrPasswordPolicy = errors.New("password does not meet the password policy")
+
+// PasswordPolicyError is returned when a password violates the password policy. It lists
+// every violated rule and unwraps to ErrPasswordPolicy.
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrPasswordPolicy, strings.Join(e.Violations, "; "))
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrPasswordPolicy
+}
+
+// PasswordPolicy contains the rules a password must follow.
+type PasswordPolicy struct {
+	MinLength        int     `env:"PASSWORD_MIN_LENGTH"        envDefault:"8"`
+	MaxLength        int     `env:"PASSWORD_MAX_LENGTH"        envDefault:"128"`
+	RequireUppercase bool    `env:"PASSWORD_REQUIRE_UPPERCASE" envDefault:"true"`
+	RequireLowercase bool    `env:"PASSWORD_REQUIRE_LOWERCASE" envDefault:"true"`
+	RequireDigit     bool    `env:"PASSWORD_REQUIRE_DIGIT"     envDefault:"true"`
+	RequireSymbol    bool    `env:"PASSWORD_REQUIRE_SYMBOL"`
+	MinEntropy       float64 `env:"PASSWORD_MIN_ENTROPY"       envDefault:"40"`
+}
+
+// NewPasswordPolicy creates a new PasswordPolicy instance from environment variables.
+func NewPasswordPolicy(logger *zerolog.Logger) *PasswordPolicy {
+	policy, err := env.ParseAs[PasswordPolicy]()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to parse environment variables")
+	}
+
+	return &policy
+}
+
+// Validate checks the password against the policy. The personal information, such as the
+// email address and full name of the user, must not appear anywhere in the password.
+func (p *PasswordPolicy) Validate(password string, personalInfo ...string) error {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters long", p.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireUppercase && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.RequireLowercase && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if containsPersonalInfo(password, personalInfo) {
+		violations = append(violations, "must not contain your email address or name")
+	}
+
+	if PasswordEntropy(password) < p.MinEntropy {
+		violations = append(violations, "is too easy to guess")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+// PasswordEntropy estimates the strength of a password in bits from the character classes
+// it uses. Repeated characters are only counted once so that "aaaaaaaa" scores as weak.
+func PasswordEntropy(password string) float64 {
+	var poolSize int
+	var hasUpper, hasLower, hasDigit, hasSymbol, hasOther bool
+
+	distinct := make(map[rune]struct{})
+	for _, r := range password {
+		distinct[r] = struct{}{}
+
+		switch {
+		case r < unicode.MaxASCII && unicode.IsUpper(r):
+			hasUpper = true
+		case r < unicode.MaxASCII && unicode.IsLower(r):
+			hasLower = true
+		case r < unicode.MaxASCII && unicode.IsDigit(r):
+			hasDigit = true
+		case r < unicode.MaxASCII:
+			hasSymbol = true
+		default:
+			hasOther = true
+		}
+	}
+
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{
+		{hasUpper, 26},
+		{hasLower, 26},
+		{hasDigit, 10},
+		{hasSymbol, 33},
+		{hasOther, 100},
+	} {
+		if class.present {
+			poolSize += class.size
+		}
+	}
+
+	if poolSize == 0 {
+		return 0
+	}
+
+	return float64(len(distinct)) * math.Log2(float64(poolSize))
+}
+
+// containsPersonalInfo reports whether the password contains any of the personal information,
+// including the local part of email addresses and the individual words of names.
+func containsPersonalInfo(password string, personalInfo []string) bool {
+	password = strings.ToLower(password)
+
+	for _, info := range personalInfo {
+		info = strings.ToLower(strings.TrimSpace(info))
+
+		candidates := []string{info}
+		if local, _, ok := strings.Cut(info, "@"); ok {
+			candidates = append(candidates, local)
+		}
+		candidates = append(candidates, strings.Fields(info)...)
+
+		for _, candidate := range candidates {
+			if utf8.RuneCountInString(candidate) >= minPersonalInfoLength && strings.Contains(password, candidate) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
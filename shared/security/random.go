@@ -0,0 +1,59 @@
+package security
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"math/big"
+	"strings"
+)
+
+// GenerateNumericCode generates a cryptographically secure random numeric code with the given number of digits.
+func GenerateNumericCode(digits int) (string, error) {
+	var code strings.Builder
+	code.Grow(digits)
+
+	for range digits {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(byte('0' + n.Int64()))
+	}
+
+	return code.String(), nil
+}
+
+// GenerateRandomToken generates a URL-safe random token from the given number of random bytes.
+func GenerateRandomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// recoveryCodeAlphabet excludes characters that are easily confused with each other.
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateRecoveryCode generates a random human-readable recovery code in the form xxxxx-xxxxx.
+func GenerateRecoveryCode() (string, error) {
+	const groupSize = 5
+
+	var code strings.Builder
+	code.Grow(2*groupSize + 1)
+
+	for i := range 2 * groupSize {
+		if i == groupSize {
+			code.WriteByte('-')
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+
+	return code.String(), nil
+}
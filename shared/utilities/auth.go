@@ -0,0 +1,23 @@
+package utilities
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ReadBearerToken extracts the bearer token from the Authorization header of the request.
+func ReadBearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(header[len(prefix):])
+	if token == "" {
+		return "", false
+	}
+
+	return token, true
+}
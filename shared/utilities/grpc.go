@@ -1,9 +1,17 @@
 package utilities
 
 import (
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/vasapolrittideah/optimize-api/shared/contract"
 )
 
 // RegisterHealthServer registers the gRPC health check service.
@@ -12,3 +20,53 @@ func RegisterHealthServer(grpcServer *grpc.Server) {
 	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
 	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
 }
+
+// NewGRPCErrorWithReason creates a gRPC error that carries an application-specific
+// error code as its reason, so clients can tell apart errors sharing the same gRPC code.
+func NewGRPCErrorWithReason(code codes.Code, reason, message string) error {
+	st := status.New(code, message)
+
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// NewGRPCErrorWithRetryInfo creates a gRPC error like NewGRPCErrorWithReason that also
+// tells clients how long to wait before retrying.
+func NewGRPCErrorWithRetryInfo(code codes.Code, reason, message string, retryAfter time.Duration) error {
+	st := status.New(code, message)
+
+	detailed, err := st.WithDetails(
+		&errdetails.ErrorInfo{Reason: reason},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)},
+	)
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// NewGRPCValidationError creates an InvalidArgument gRPC error that carries field-level
+// validation errors, so the API gateway can report them like its own validation errors.
+func NewGRPCValidationError(message string, details []contract.APIValidationError) error {
+	st := status.New(codes.InvalidArgument, message)
+
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(details))
+	for _, detail := range details {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       detail.Field,
+			Description: detail.Message,
+		})
+	}
+
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
@@ -1,10 +1,13 @@
 package utilities
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/rs/zerolog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -34,13 +37,18 @@ func WriteInternalErrorResponse(w http.ResponseWriter, r *http.Request, grpcErro
 		Msg("internal error occurred")
 
 	st := status.Convert(grpcError)
-	errorCode := errorCodeFromGRPCCode(st.Code())
+	errorCode := errorCodeFromStatus(st)
 	httpStatus := httpStatusFromGRPCCode(st.Code())
 
+	if retryAfter, ok := retryAfterFromStatus(st); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+
 	apiResp := &contract.APIResponse{
 		Error: &contract.APIError{
 			Code:    errorCode,
 			Message: st.Message(),
+			Details: validationErrorsFromStatus(st),
 		},
 		Timestamp: time.Now(),
 	}
@@ -76,6 +84,29 @@ func WriteRequestErrorResponse(w http.ResponseWriter, r *http.Request, message s
 	}
 }
 
+// WriteUnauthorizedErrorResponse writes an unauthorized error response with the provided message.
+func WriteUnauthorizedErrorResponse(w http.ResponseWriter, r *http.Request, message string, logger *zerolog.Logger) {
+	logger.Error().
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Msg("unauthorized request")
+
+	apiResp := &contract.APIResponse{
+		Error: &contract.APIError{
+			Code:    contract.ErrorCodeUnauthorized,
+			Message: message,
+		},
+		Timestamp: time.Now(),
+	}
+
+	if err := WriteJSON(w, http.StatusUnauthorized, apiResp); err != nil {
+		logger.Error().Err(err).
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Msg("failed to write error response")
+	}
+}
+
 // WriteValidationErrorResponse writes a validation error response with the provided details.
 func WriteValidationErrorResponse(
 	w http.ResponseWriter,
@@ -105,6 +136,52 @@ func WriteValidationErrorResponse(
 	}
 }
 
+// errorCodeFromStatus returns the error code carried in the status details,
+// falling back to the code mapped from the gRPC code.
+func errorCodeFromStatus(st *status.Status) string {
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			if detail.GetReason() != "" {
+				return detail.GetReason()
+			}
+		case *errdetails.BadRequest:
+			return contract.ErrorCodeValidation
+		}
+	}
+
+	return errorCodeFromGRPCCode(st.Code())
+}
+
+// validationErrorsFromStatus returns the field violations carried in the status details.
+func validationErrorsFromStatus(st *status.Status) []contract.APIValidationError {
+	var errs []contract.APIValidationError
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				errs = append(errs, contract.APIValidationError{
+					Field:   violation.GetField(),
+					Message: violation.GetDescription(),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// retryAfterFromStatus returns the retry delay carried in the status details, rounded
+// up to whole seconds as expected by the Retry-After header.
+func retryAfterFromStatus(st *status.Status) (int, bool) {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			return int(math.Ceil(info.GetRetryDelay().AsDuration().Seconds())), true
+		}
+	}
+
+	return 0, false
+}
+
 // errorCodeFromGRPCCode maps gRPC codes to application-specific error codes.
 func errorCodeFromGRPCCode(code codes.Code) string {
 	switch code {
@@ -126,6 +203,8 @@ func errorCodeFromGRPCCode(code codes.Code) string {
 		return contract.ErrorCodeForbidden
 	case codes.ResourceExhausted:
 		return contract.ErrorCodeRateLimit
+	case codes.Unauthenticated:
+		return contract.ErrorCodeUnauthorized
 	default:
 		return contract.ErrorCodeInternal
 	}
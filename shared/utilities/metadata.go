@@ -0,0 +1,140 @@
+package utilities
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	metadataKeyClientIP        = "x-client-ip"
+	metadataKeyClientUserAgent = "x-client-user-agent"
+)
+
+// ClientMetadata contains information about the client that originated a request.
+type ClientMetadata struct {
+	IPAddress string
+	UserAgent string
+}
+
+// ParseTrustedProxies parses the addresses of trusted proxies, given either as single IP
+// addresses or as CIDR ranges.
+func ParseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// ForwardClientMetadata returns an HTTP middleware that attaches the client IP address and
+// user agent to the outgoing gRPC metadata of the request context. The X-Forwarded-For header
+// is only honored for requests that come through one of the trusted proxies, since any client
+// can set it to an address of its choosing.
+func ForwardClientMetadata(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := metadata.AppendToOutgoingContext(
+				r.Context(),
+				metadataKeyClientIP, clientIPAddress(r, trustedProxies),
+				metadataKeyClientUserAgent, r.UserAgent(),
+			)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// clientIPAddress resolves the address of the client. Starting from the peer address, it walks
+// X-Forwarded-For from the nearest hop backwards for as long as the hops are trusted proxies,
+// and returns the first address that was not added by a trusted proxy.
+func clientIPAddress(r *http.Request, trustedProxies []netip.Prefix) string {
+	ipAddress := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ipAddress = host
+	}
+
+	if !isTrustedProxy(ipAddress, trustedProxies) {
+		return ipAddress
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+
+		ipAddress = hop
+		if !isTrustedProxy(hop, trustedProxies) {
+			break
+		}
+	}
+
+	return ipAddress
+}
+
+func isTrustedProxy(ipAddress string, trustedProxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientMetadataFromContext extracts the client information forwarded in the incoming gRPC metadata.
+func ClientMetadataFromContext(ctx context.Context) ClientMetadata {
+	var clientMetadata ClientMetadata
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return clientMetadata
+	}
+
+	if values := md.Get(metadataKeyClientIP); len(values) > 0 {
+		clientMetadata.IPAddress = values[0]
+	}
+	if values := md.Get(metadataKeyClientUserAgent); len(values) > 0 {
+		clientMetadata.UserAgent = values[0]
+	}
+
+	return clientMetadata
+}
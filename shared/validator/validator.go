@@ -2,6 +2,7 @@ package validator
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 
@@ -11,6 +12,7 @@ import (
 	enTrans "github.com/go-playground/validator/v10/translations/en"
 
 	"github.com/vasapolrittideah/optimize-api/shared/contract"
+	"github.com/vasapolrittideah/optimize-api/shared/security"
 )
 
 var (
@@ -40,6 +42,11 @@ func translateErrorMessage(validationErrors validator.ValidationErrors) []contra
 			Value:   err.Value(),
 		}
 
+		// Passwords are never echoed back to the client.
+		if err.Tag() == "password" {
+			invalidField.Value = nil
+		}
+
 		errs = append(errs, invalidField)
 	}
 
@@ -63,3 +70,46 @@ func registerTranslation() ut.Translator {
 
 	return trans
 }
+
+// personalInfoFields lists the struct fields a password is checked against when they
+// are declared next to it.
+var personalInfoFields = []string{"Email", "FullName"}
+
+// RegisterPasswordPolicy registers the "password" tag, which validates a field against the
+// password policy. Email and FullName fields of the same struct count as personal information.
+func RegisterPasswordPolicy(policy *security.PasswordPolicy) {
+	_ = val.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		var personalInfo []string
+		parent := reflect.Indirect(fl.Parent())
+		if parent.Kind() == reflect.Struct {
+			for _, name := range personalInfoFields {
+				if field := parent.FieldByName(name); field.IsValid() && field.Kind() == reflect.String {
+					personalInfo = append(personalInfo, field.String())
+				}
+			}
+		}
+
+		return policy.Validate(fl.Field().String(), personalInfo...) == nil
+	})
+
+	_ = val.RegisterTranslation(
+		"password",
+		trans,
+		func(ut ut.Translator) error {
+			return ut.Add("password", "{0} {1}", true)
+		},
+		func(ut ut.Translator, fe validator.FieldError) string {
+			// The translation has no access to the personal information, so a password
+			// that passes every other rule failed because it contains some.
+			message := "must not contain your email address or name"
+
+			var policyErr *security.PasswordPolicyError
+			if err := policy.Validate(fmt.Sprint(fe.Value())); errors.As(err, &policyErr) {
+				message = strings.Join(policyErr.Violations, ", ")
+			}
+
+			t, _ := ut.T("password", fe.Field(), message)
+			return t
+		},
+	)
+}